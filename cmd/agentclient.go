@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// agent api address given on the command line, instead of reading it from the configuration
+var agentAddress string
+var agentName string
+
+// addAgentFlags adds the flags locating a running agent to a command talking to it
+func addAgentFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&cfgFile, "config", "c", "config.json", "configuration file the agent was started with")
+	cmd.Flags().StringVarP(&agentAddress, "address", "a", "", "agent api address, e.g. http://127.0.0.1:21780 (default from transportSettings of the configuration)")
+	cmd.Flags().StringVarP(&agentName, "name", "n", "", "container name (default from the configuration)")
+}
+
+// agentClient calls the api of a running agent
+type agentClient struct {
+	// url of the container resources, e.g. http://127.0.0.1:21780/mashling
+	url        string
+	httpClient *http.Client
+}
+
+// newAgentClient locates the agent from the address and name flags, falling back to the configuration file
+func newAgentClient() (*agentClient, error) {
+	client := &agentClient{
+		// long enough for an action to wait for a heartbeat and the component process to stop
+		httpClient: &http.Client{Timeout: time.Minute},
+	}
+	if agentAddress != "" && agentName != "" {
+		client.url = strings.TrimSuffix(agentAddress, "/") + "/" + agentName
+		return client, nil
+	}
+
+	cd, err := agentConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read configuration %s, set --address and --name: %s", cfgFile, err)
+	}
+	if agentName != "" {
+		cd.Name = agentName
+	}
+	client.url = cd.AgentURL()
+	if agentAddress != "" {
+		client.url = strings.TrimSuffix(agentAddress, "/") + "/" + cd.Name
+	}
+	return client, nil
+}
+
+// agentConfig reads the name and transport settings of the agent from the configuration file,
+// with environment overrides applied as the agent does
+func agentConfig() (config.ContainerDaemon, error) {
+	var cd config.ContainerDaemon
+	err := readConfig()
+	if err != nil {
+		return cd, err
+	}
+	err = viper.Unmarshal(&cd)
+	if err != nil {
+		return cd, err
+	}
+	err = config.ApplyEnvironment(&cd, os.Environ())
+	if err != nil {
+		return cd, err
+	}
+	if cd.Name == "" {
+		return cd, errors.New("name is not set")
+	}
+	return cd, nil
+}
+
+// agentError error response of the agent api
+type agentError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *agentError) Error() string {
+	return fmt.Sprintf("%s (%d %s)", e.Message, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// get decodes the json resource at path of the container into v
+func (client *agentClient) get(path string, v interface{}) error {
+	body, err := client.do(http.MethodGet, path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// do sends a request for a resource of the container and returns the response body,
+// error responses are returned as agentError
+func (client *agentClient) do(method, path string) ([]byte, error) {
+	req, err := http.NewRequest(method, client.url+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		return nil, &agentError{StatusCode: res.StatusCode, Message: strings.TrimSpace(string(body))}
+	}
+	return body, nil
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/rameshpolishetti/mlca/internal/core/service/lifecycleservice"
+	"github.com/spf13/cobra"
+)
+
+// exit codes of the ctl commands
+const (
+	exitError             = 1
+	exitUnknownComponent  = 3
+	exitIllegalTransition = 4
+)
+
+// ctlActions descriptions of the actions a component can be controlled with
+var ctlActions = map[string]string{
+	lifecycleservice.ActionStart:   "Start a stopped component",
+	lifecycleservice.ActionStop:    "Stop a component until it is started again",
+	lifecycleservice.ActionRestart: "Stop a component and run its lifecycle again",
+	lifecycleservice.ActionReload:  "Rebuild the configuration of an ACTIVE component and reload it",
+	lifecycleservice.ActionDisable: "Stop a component until it is enabled again, also across agent restarts",
+	lifecycleservice.ActionEnable:  "Start a disabled component",
+}
+
+var logsTail int
+
+func init() {
+	for _, action := range lifecycleservice.Actions {
+		actionCmd := &cobra.Command{
+			Use:   action + " COMPONENT",
+			Short: ctlActions[action],
+			Args:  cobra.ExactArgs(1),
+			Run:   ctlAction,
+		}
+		addAgentFlags(actionCmd)
+		ctlCmd.AddCommand(actionCmd)
+	}
+
+	addAgentFlags(logsCmd)
+	logsCmd.Flags().IntVar(&logsTail, "tail", 100, "number of lines to show, 0 for all of them")
+	ctlCmd.AddCommand(logsCmd)
+
+	rootCmd.AddCommand(ctlCmd)
+}
+
+var ctlCmd = &cobra.Command{
+	Use:   "ctl",
+	Short: "Control the components of a running agent",
+	Long: `Ctl calls the api of a running agent to control one of its managed components,
+e.g. ctl restart TMG-LFA. The agent is located as with status.
+
+Exit codes: 0 the action was applied, 1 the agent could not be reached or the
+action failed, 3 there is no such component, 4 the action is not allowed in
+the current state of the component.`,
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs COMPONENT",
+	Short: "Show the captured output of a component",
+	Args:  cobra.ExactArgs(1),
+	Run:   ctlLogs,
+}
+
+func ctlAction(cmd *cobra.Command, args []string) {
+	action, component := cmd.Name(), args[0]
+	client, err := newAgentClient()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
+
+	cs := lifecycleservice.ComponentStatus{}
+	body, err := client.do(http.MethodPost, "/components/"+component+"/"+action)
+	if err == nil {
+		err = json.Unmarshal(body, &cs)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s %s: %s\n", action, component, err)
+		os.Exit(ctlExitCode(err))
+	}
+	fmt.Printf("%s %s: %s\n", action, cs.Name, cs.State)
+}
+
+func ctlLogs(cmd *cobra.Command, args []string) {
+	component := args[0]
+	client, err := newAgentClient()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
+
+	body, err := client.do(http.MethodGet, "/components/"+component+"/logs?tail="+strconv.Itoa(logsTail))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logs %s: %s\n", component, err)
+		os.Exit(ctlExitCode(err))
+	}
+	os.Stdout.Write(body)
+}
+
+// ctlExitCode maps an agent api error to the exit code of the ctl commands
+func ctlExitCode(err error) int {
+	if e, ok := err.(*agentError); ok {
+		switch e.StatusCode {
+		case http.StatusNotFound:
+			return exitUnknownComponent
+		case http.StatusConflict:
+			return exitIllegalTransition
+		}
+	}
+	return exitError
+}
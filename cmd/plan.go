@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/rameshpolishetti/mlca/internal/core/component"
+	"github.com/rameshpolishetti/mlca/internal/core/service"
+	"github.com/rameshpolishetti/mlca/logger"
+	"github.com/spf13/viper"
+)
+
+// plan prints what start would do with the configuration: the components in start order, their
+// configuration files rendered into a temporary directory and the registration payloads. No process
+// is launched and neither the registry nor the cluster manager is contacted.
+func plan(cConfig config.ContainerDaemon) error {
+	dir, err := ioutil.TempDir("", "mlca-plan-")
+	if err != nil {
+		return err
+	}
+
+	// render into the temporary directory, components see each other with the overridden paths
+	components := make([]config.ManagedComponent, len(cConfig.Components))
+	for i, mc := range cConfig.Components {
+		if mc.ConfigFile != "" {
+			mc.ConfigFile = filepath.Join(dir, mc.Name, filepath.Base(mc.ConfigFile))
+		}
+		components[i] = mc
+	}
+	cConfig.Components = components
+
+	ordered, err := config.DependencyOrder(cConfig.Components)
+	if err != nil {
+		return err
+	}
+
+	// only warnings and errors of the components are of interest
+	logger.SetLevels("warn")
+
+	rService := service.NewRegistryProxyService(cConfig)
+	s := component.Services{
+		Registry:  rService,
+		Container: &cConfig,
+		DryRun:    true,
+	}
+
+	fmt.Printf("configuration %s is valid, dry run of container [%s]\n\n", viper.ConfigFileUsed(), cConfig.Name)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ORDER\tCOMPONENT\tFACTORY\tDEPENDS ON\tCONFIGURATION")
+	failed := 0
+	for i, mc := range ordered {
+		factory, ok := component.GetFactory(mc.Factory)
+		if !ok {
+			return fmt.Errorf("managed component factory %s not found", mc.Factory)
+		}
+		mc.ContainerInstance = rService.ContainerInstance()
+		c := factory(mc, s)
+
+		rendered := "-"
+		if mc.ConfigFile != "" {
+			rendered = mc.ConfigFile
+		}
+		if !c.BuildConfiguration() {
+			rendered = "FAILED to render"
+			failed++
+		}
+		dependsOn := "-"
+		if len(mc.DependsOn) > 0 {
+			dependsOn = strings.Join(mc.DependsOn, ",")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", i+1, mc.Name, mc.Factory, dependsOn, rendered)
+	}
+	tw.Flush()
+	fmt.Printf("\nconfiguration files rendered into %s\n\n", dir)
+
+	url, payload := rService.Registration()
+	printRequest("POST", url, payload)
+	for _, mc := range ordered {
+		url, payload := rService.ComponentRegistration(mc)
+		printRequest("POST", url, payload)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("unable to render the configuration of %d components", failed)
+	}
+	return nil
+}
+
+func printRequest(method, url string, payload interface{}) {
+	body, _ := json.MarshalIndent(payload, "", "  ")
+	fmt.Printf("%s %s\n%s\n\n", method, url, body)
+}
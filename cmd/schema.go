@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/spf13/cobra"
+)
+
+var schemaOutput string
+
+func init() {
+	schemaCmd.Flags().StringVarP(&schemaOutput, "output", "o", "", "write the schema to this file instead of stdout")
+
+	rootCmd.AddCommand(schemaCmd)
+}
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print JSON Schema of the configuration",
+	Long: `Schema prints the JSON Schema of the container configuration, generated from the
+configuration types, for validating JSON, YAML and TOML configuration files in editors.`,
+	Run: schema,
+}
+
+func schema(cmd *cobra.Command, args []string) {
+	data, err := config.JSONSchema()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to generate schema: %s\n", err)
+		os.Exit(1)
+	}
+
+	if schemaOutput == "" {
+		fmt.Printf("%s\n", data)
+		return
+	}
+	err = ioutil.WriteFile(schemaOutput, append(data, '\n'), 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to write schema: %s\n", err)
+		os.Exit(1)
+	}
+}
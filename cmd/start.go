@@ -2,53 +2,114 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/rameshpolishetti/mlca/internal/core/common/config"
 	"github.com/rameshpolishetti/mlca/internal/core/common/util"
+	"github.com/rameshpolishetti/mlca/internal/core/common/version"
+	"github.com/rameshpolishetti/mlca/internal/core/component"
 	"github.com/rameshpolishetti/mlca/internal/core/container"
 	"github.com/rameshpolishetti/mlca/logger"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var log = logger.GetLogger("cmd")
 var cfgFile string
+var startDryRun bool
 
 func init() {
-	cobra.OnInitialize(initConfig)
 	startCmd.Flags().StringVarP(&cfgFile, "config", "c", "config.json", "configuration file")
+	startCmd.Flags().BoolVar(&startDryRun, "dry-run", false, "print what start would do, without launching components or contacting the registry")
 	// startCmd.MarkFlagRequired("config")
 
 	rootCmd.AddCommand(startCmd)
 }
 
-func initConfig() {
-	if cfgFile != "" {
-		viper.SetConfigFile(cfgFile)
-	} else {
-		log.Panicln("config file is required")
-	}
-	if err := viper.ReadInConfig(); err != nil {
-		log.Panicln("Can't read config:", err)
+// readConfig reads the configuration file given by the config flag
+func readConfig() error {
+	if cfgFile == "" {
+		return errors.New("config file is required")
 	}
+	viper.SetConfigFile(cfgFile)
+	return viper.ReadInConfig()
 }
 
 var startCmd = &cobra.Command{
 	Use:   "start",
 	Short: "Run containerized application",
-	Long:  `Start runs containerized application`,
-	Run:   run,
+	Long: `Start runs containerized application.
+
+With --dry-run the configuration is loaded and validated, component factories and the
+start order are resolved, component configuration files are rendered into a temporary
+directory and the registration payloads are printed; no process is launched and
+neither the registry nor the cluster manager is contacted.`,
+	Run: run,
 }
 
 func run(cmd *cobra.Command, args []string) {
+	if err := readConfig(); err != nil {
+		log.Panicln("Can't read config:", err)
+	}
 
 	// load container configuration
+	cConfig, err := loadContainerConfig(viper.GetViper())
+	if err != nil {
+		log.Errorf("unable to load container configuration from %s: %s", viper.ConfigFileUsed(), err)
+		os.Exit(1)
+	}
+
+	// tag log lines with the resolved identity
+	logger.SetMetadata(cConfig.Cluster, cConfig.Zone, cConfig.IP)
+
+	if startDryRun {
+		err = plan(cConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "dry run failed: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	err = container.ConfigureLogging(cConfig)
+	if err != nil {
+		log.Errorf("unable to configure logging: %s", err)
+		os.Exit(1)
+	}
+
+	cfgString, _ := json.MarshalIndent(cConfig, "", " ")
+	log.Infof("Start the container [%s] with mlca %s and configuration: %s", cConfig.Name, version.Get(), cfgString)
+
+	ca := container.NewContainerAgent(cConfig)
+
+	// reload the configuration when the file changes or on SIGHUP
+	configFile := viper.ConfigFileUsed()
+	ca.SetConfigLoader(func() (config.ContainerDaemon, error) {
+		return reloadContainerConfig(configFile)
+	})
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		log.Infof("configuration file %s changed", e.Name)
+		ca.RequestConfigReload()
+	})
+	viper.WatchConfig()
+	watchComponentsDir(cConfig.ComponentsDirPath(filepath.Dir(configFile)), ca.RequestConfigReload)
+
+	ca.Initialize()
+	ca.Start()
+}
+
+// loadContainerConfig unmarshals the container configuration read by v, adds host details,
+// applies environment overrides, merges the component drop-in files and validates it
+func loadContainerConfig(v *viper.Viper) (config.ContainerDaemon, error) {
 	var cConfig config.ContainerDaemon
-	err := viper.Unmarshal(&cConfig)
+	err := v.Unmarshal(&cConfig)
 	if err != nil {
-		log.Errorf("unable to load container configuration")
+		return cConfig, err
 	}
 
 	// load host details
@@ -59,10 +120,77 @@ func run(cmd *cobra.Command, args []string) {
 	}
 	cConfig.Node = hName
 
-	cfgString, _ := json.MarshalIndent(cConfig, "", " ")
-	log.Infof("Start the container [%s] with configuration: %s", cConfig.Name, cfgString)
+	// environment overrides and ${VAR:-default} expansion, componentsDir is resolved before the drop-ins are loaded
+	environ := os.Environ()
+	err = config.ApplyEnvironment(&cConfig, environ)
+	if err != nil {
+		return cConfig, err
+	}
 
-	ca := container.NewContainerAgent(cConfig)
-	ca.Initialize()
-	ca.Start()
+	// merge components declared in the drop-in directory
+	err = cConfig.LoadDropIns(filepath.Dir(v.ConfigFileUsed()), environ)
+	if err != nil {
+		return cConfig, err
+	}
+
+	// components declaring only their type, as before the factory field, get the factory of the type
+	cConfig.ResolveFactories(component.FactoryTypes())
+
+	err = cConfig.Validate(component.FactoryNames(), component.FactoryTypes())
+	if err != nil {
+		return cConfig, err
+	}
+
+	return cConfig, nil
+}
+
+// reloadContainerConfig reads the configuration file again and loads the container configuration.
+// It runs on the lifecycle loop while the file watcher of the global viper may read the file,
+// so the file is read with a viper of its own.
+func reloadContainerConfig(configFile string) (config.ContainerDaemon, error) {
+	v := viper.New()
+	v.SetConfigFile(configFile)
+	if err := v.ReadInConfig(); err != nil {
+		return config.ContainerDaemon{}, err
+	}
+	return loadContainerConfig(v)
+}
+
+// watchComponentsDir requests a configuration reload when a file of the drop-in directory changes
+func watchComponentsDir(dir string, onChange func()) {
+	if dir == "" {
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Errorf("unable to watch componentsDir %s: %s", dir, err)
+		return
+	}
+	err = watcher.Add(dir)
+	if err != nil {
+		log.Errorf("unable to watch componentsDir %s: %s", dir, err)
+		watcher.Close()
+		return
+	}
+
+	go func() {
+		for {
+			select {
+			case e, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if e.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+					log.Infof("component drop-in %s changed", e.Name)
+					onChange()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Errorf("componentsDir watcher error: %s", err)
+			}
+		}
+	}()
 }
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testConfig = `{
+  "name": "mashling",
+  "componentType": "trafficmanagers",
+  "cluster": "cluster",
+  "zone": "zone",
+  "inboxes": {"registry": "http://registry:21180"},
+  "transportSettings": {"port": 21780},
+  "componentsDir": "${DROPIN_DIR:-conf.d}",
+  "components": [
+    {"name": "TMG-Microgateway", "type": "Microgateway", "qualifier": "microgateway", "script": "mashling-gateway"}
+  ]
+}`
+
+func writeFile(t *testing.T, file, content string) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setenv(t *testing.T, name, value string) func() {
+	if err := os.Setenv(name, value); err != nil {
+		t.Fatal(err)
+	}
+	return func() { os.Unsetenv(name) }
+}
+
+// The drop-in directory is resolved with the environment before it is loaded, so the
+// components come from the same directory start watches.
+func TestLoadContainerConfigComponentsDirFromEnvironment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mlca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "config.json")
+	writeFile(t, configFile, testConfig)
+	writeFile(t, filepath.Join(dir, "conf.d", "lfa.json"), `{"name": "default-lfa", "type": "Log", "qualifier": "lfa", "script": "startup_lfa.sh"}`)
+	writeFile(t, filepath.Join(dir, "env.d", "lfa.json"), `{"name": "TMG-LFA", "type": "Log", "qualifier": "lfa", "script": "${LFA_HOME:-/opt/lfa}/startup_lfa.sh"}`)
+	writeFile(t, filepath.Join(dir, "override.d", "lfa.yaml"), "name: override-lfa\ntype: Log\nqualifier: lfa\nscript: startup_lfa.sh\n")
+
+	load := func() (string, string, string) {
+		cConfig, err := reloadContainerConfig(configFile)
+		if err != nil {
+			t.Fatalf("reloadContainerConfig() error = %v", err)
+		}
+		if len(cConfig.Components) != 2 {
+			t.Fatalf("loaded %d components, want the main one and one drop-in", len(cConfig.Components))
+		}
+		dropIn := cConfig.Components[1]
+		return cConfig.ComponentsDirPath(dir), dropIn.Name, dropIn.Script
+	}
+
+	watched, name, _ := load()
+	if watched != filepath.Join(dir, "conf.d") || name != "default-lfa" {
+		t.Errorf("without environment loaded %s from %s, want default-lfa from conf.d", name, watched)
+	}
+
+	defer setenv(t, "DROPIN_DIR", "env.d")()
+	defer setenv(t, "LFA_HOME", "/srv/lfa")()
+	watched, name, script := load()
+	if watched != filepath.Join(dir, "env.d") || name != "TMG-LFA" {
+		t.Errorf("with DROPIN_DIR loaded %s from %s, want TMG-LFA from env.d", name, watched)
+	}
+	if script != "/srv/lfa/startup_lfa.sh" {
+		t.Errorf("drop-in script = %s, want the expanded /srv/lfa/startup_lfa.sh", script)
+	}
+
+	defer setenv(t, "MLCA_COMPONENTSDIR", filepath.Join(dir, "override.d"))()
+	defer setenv(t, "MLCA_COMPONENTS_OVERRIDE_LFA_SCRIPT", "run_lfa.sh")()
+	watched, name, script = load()
+	if watched != filepath.Join(dir, "override.d") || name != "override-lfa" {
+		t.Errorf("with MLCA_COMPONENTSDIR loaded %s from %s, want override-lfa from override.d", name, watched)
+	}
+	if script != "run_lfa.sh" {
+		t.Errorf("drop-in script = %s, want the overridden run_lfa.sh", script)
+	}
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rameshpolishetti/mlca/internal/core/container"
+	"github.com/spf13/cobra"
+)
+
+var statusOutput string
+var statusWatch bool
+var statusInterval time.Duration
+
+func init() {
+	addAgentFlags(statusCmd)
+	statusCmd.Flags().StringVarP(&statusOutput, "output", "o", "table", "output format, table or json")
+	statusCmd.Flags().BoolVarP(&statusWatch, "watch", "w", false, "keep watching the agent and print the status whenever it changes")
+	statusCmd.Flags().DurationVar(&statusInterval, "interval", 2*time.Second, "interval between polls with --watch")
+
+	rootCmd.AddCommand(statusCmd)
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show status of a running agent",
+	Long: `Status connects to the api of a running agent and prints the container status
+and the state of each managed component. The agent is located from the
+transportSettings and name of the configuration file, or from --address and --name.
+With --watch the status is printed again whenever it changes, until interrupted.`,
+	Run: status,
+}
+
+func status(cmd *cobra.Command, args []string) {
+	if statusOutput != "table" && statusOutput != "json" {
+		fmt.Fprintf(os.Stderr, "unknown output format %s, must be table or json\n", statusOutput)
+		os.Exit(2)
+	}
+	client, err := newAgentClient()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if !statusWatch {
+		mca := container.ModelCA{}
+		err = client.get("/status", &mca)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to get status from %s: %s\n", client.url, err)
+			os.Exit(1)
+		}
+		printStatus(os.Stdout, mca, false)
+		return
+	}
+
+	// print the status whenever it changes, and once when the agent becomes unreachable
+	var last []byte
+	var lastErr string
+	for {
+		mca := container.ModelCA{}
+		err = client.get("/status", &mca)
+		if err != nil {
+			if err.Error() != lastErr {
+				fmt.Fprintf(os.Stderr, "%s unable to get status from %s: %s\n", time.Now().Format(time.RFC3339), client.url, err)
+				lastErr = err.Error()
+				last = nil
+			}
+		} else {
+			lastErr = ""
+			current, _ := json.Marshal(mca)
+			if !bytes.Equal(current, last) {
+				printStatus(os.Stdout, mca, true)
+				last = current
+			}
+		}
+		time.Sleep(statusInterval)
+	}
+}
+
+// printStatus writes the container status in the selected output format, with --watch json is written
+// as one line per change and tables are headed by the time of the change
+func printStatus(w io.Writer, mca container.ModelCA, watch bool) {
+	if statusOutput == "json" {
+		if watch {
+			line, _ := json.Marshal(mca)
+			fmt.Fprintf(w, "%s\n", line)
+			return
+		}
+		out, _ := json.MarshalIndent(mca, "", "  ")
+		fmt.Fprintf(w, "%s\n", out)
+		return
+	}
+
+	if watch {
+		fmt.Fprintf(w, "%s\n", time.Now().Format(time.RFC3339))
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "CONTAINER\t%s\n", mca.Name)
+	fmt.Fprintf(tw, "STATUS\t%s (%s)\n", mca.Status, mca.Policy)
+	tw.Flush()
+	fmt.Fprintln(w)
+
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMPONENT\tSTATE\tCRITICAL\tOPTIONAL")
+	for _, c := range mca.Components {
+		fmt.Fprintf(tw, "%s\t%s\t%t\t%t\n", c.Name, c.State, c.Critical, c.Optional)
+	}
+	tw.Flush()
+	if watch {
+		fmt.Fprintln(w)
+	}
+}
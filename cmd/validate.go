@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	validateCmd.Flags().StringVarP(&cfgFile, "config", "c", "config.json", "configuration file")
+
+	rootCmd.AddCommand(validateCmd)
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate configuration file",
+	Long: `Validate loads the configuration file as start does, checks it together with
+the component dependency graph and prints the resolved configuration.
+It exits with a non-zero code when the configuration is invalid.`,
+	Run: validate,
+}
+
+func validate(cmd *cobra.Command, args []string) {
+	err := readConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to read configuration %s: %s\n", cfgFile, err)
+		os.Exit(1)
+	}
+
+	cConfig, err := loadContainerConfig(viper.GetViper())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "configuration %s is invalid: %s\n", viper.ConfigFileUsed(), err)
+		os.Exit(1)
+	}
+
+	ordered, err := config.DependencyOrder(cConfig.Components)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "configuration %s is invalid: %s\n", viper.ConfigFileUsed(), err)
+		os.Exit(1)
+	}
+	startOrder := make([]string, 0, len(ordered))
+	for _, mc := range ordered {
+		startOrder = append(startOrder, mc.Name)
+	}
+
+	resolved, _ := json.MarshalIndent(cConfig.WithDefaults(), "", "  ")
+	fmt.Printf("configuration %s is valid\n", viper.ConfigFileUsed())
+	fmt.Printf("component start order: %s\n", strings.Join(startOrder, " -> "))
+	fmt.Printf("resolved configuration:\n%s\n", resolved)
+}
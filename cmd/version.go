@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/version"
+	"github.com/spf13/cobra"
+)
+
+var versionOutput string
+
+func init() {
+	versionCmd.Flags().StringVarP(&versionOutput, "output", "o", "text", "output format, text or json")
+
+	rootCmd.AddCommand(versionCmd)
+}
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print version",
+	Long:  `Version prints the version, git commit and build date of the agent`,
+	Run:   printVersion,
+}
+
+func printVersion(cmd *cobra.Command, args []string) {
+	info := version.Get()
+	switch versionOutput {
+	case "text":
+		fmt.Printf("mlca %s\n", info)
+	case "json":
+		out, _ := json.MarshalIndent(info, "", "  ")
+		fmt.Printf("%s\n", out)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown output format %s, must be text or json\n", versionOutput)
+		os.Exit(2)
+	}
+}
@@ -1,55 +1,209 @@
 package config
 
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultHeartBeatInterval default interval between lifecycle reconciliations
+	DefaultHeartBeatInterval = 2000 * time.Millisecond
+	// DefaultStatusRefreshInterval default interval for re-publishing component status to the registry
+	DefaultStatusRefreshInterval = 30000 * time.Millisecond
+	// DefaultDiscoveryInterval default interval for polling the registry for upstream changes
+	DefaultDiscoveryInterval = 10000 * time.Millisecond
+	// DefaultConfigPollInterval default interval for polling the cluster manager for newer configuration
+	DefaultConfigPollInterval = 30000 * time.Millisecond
+	// DefaultReloadVerifyTimeout default time a reloaded component has to become healthy
+	DefaultReloadVerifyTimeout = 15000 * time.Millisecond
+	// DefaultHealthFailureThreshold default number of consecutive failed health probes before an ACTIVE component is restarted
+	DefaultHealthFailureThreshold = 3
+	// DefaultForwardPort default port the log forwarding component receives agent logs on
+	DefaultForwardPort = 24224
+	// DefaultHistorySize default number of state transitions kept per component
+	DefaultHistorySize = 100
+)
+
 // ContainerDaemon container configuration
 type ContainerDaemon struct {
-	Name              string             `json:"name"`
-	ComponentType     string             `json:"componentType"`
-	Domain            string             `json:"domain"`
-	Cluster           string             `json:"cluster"`
-	Zone              string             `json:"zone"`
-	Node              string             `json:"node"`
-	Port              string             `json:"port"`
-	Type              string             `json:"type"`
-	Qualifier         string             `json:"qualifier"`
-	Inboxes           map[string]string  `json:"inboxes"`
-	TransportSettings TransportSettings  `json:"transportSettings"`
-	Components        []ManagedComponent `json:"components"`
-
-	IP string
+	Name              string             `json:"name" mapstructure:"name"`
+	ComponentType     string             `json:"componentType" mapstructure:"componentType"`
+	Domain            string             `json:"domain" mapstructure:"domain"`
+	Cluster           string             `json:"cluster" mapstructure:"cluster"`
+	Zone              string             `json:"zone" mapstructure:"zone"`
+	Node              string             `json:"node" mapstructure:"node"`
+	Port              string             `json:"port" mapstructure:"port"`
+	Type              string             `json:"type" mapstructure:"type"`
+	Qualifier         string             `json:"qualifier" mapstructure:"qualifier"`
+	Inboxes           map[string]string  `json:"inboxes" mapstructure:"inboxes"`
+	TransportSettings TransportSettings  `json:"transportSettings" mapstructure:"transportSettings"`
+	Lifecycle         LifecycleSettings  `json:"lifecycle" mapstructure:"lifecycle"`
+	StatusPolicy      StatusPolicy       `json:"statusPolicy" mapstructure:"statusPolicy"`
+	CacheDir          string             `json:"cacheDir" mapstructure:"cacheDir"`
+	StateFile         string             `json:"stateFile" mapstructure:"stateFile"` // registration and component state kept across agent restarts
+	LogDir            string             `json:"logDir" mapstructure:"logDir"`
+	Components        []ManagedComponent `json:"components" mapstructure:"components"`
+	ComponentsDir     string             `json:"componentsDir" mapstructure:"componentsDir"` // conf.d style directory of component drop-in files
+	Logging           LoggingSettings    `json:"logging" mapstructure:"logging"`
+
+	IP string `json:"ip" mapstructure:"ip"`
 }
 
 // ManagedComponent component configuration
 type ManagedComponent struct {
-	Name              string            `json:"name"`
-	Type              string            `json:"type"`
-	Qualifier         string            `json:"qualifier"`
-	Script            string            `json:"script"`
-	Service           string            `json:"service"`
-	Factory           string            `json:"factory"`
-	ContainerInstance ContainerInstance `json:"container"`
+	Name           string      `json:"name" mapstructure:"name"`
+	Type           string      `json:"type" mapstructure:"type"`
+	Qualifier      string      `json:"qualifier" mapstructure:"qualifier"`
+	Script         string      `json:"script" mapstructure:"script"`
+	Service        string      `json:"service" mapstructure:"service"`
+	Factory        string      `json:"factory" mapstructure:"factory"`
+	DependsOn      []string    `json:"dependsOn" mapstructure:"dependsOn"` // components that must be ACTIVE before this one resolves
+	Critical       bool        `json:"critical" mapstructure:"critical"`   // must be ACTIVE for the container to be ACTIVE (all-required, quorum)
+	Optional       bool        `json:"optional" mapstructure:"optional"`   // never affects the container status
+	Upstreams      []string    `json:"upstreams" mapstructure:"upstreams"` // componentTypes discovered through the registry
+	Port           int         `json:"port" mapstructure:"port"`
+	ConfigTemplate string      `json:"configTemplate" mapstructure:"configTemplate"` // template the component configuration is rendered from
+	ConfigFile     string      `json:"configFile" mapstructure:"configFile"`         // rendered component configuration
+	Outputs        []LogOutput `json:"outputs" mapstructure:"outputs"`               // log forwarding outputs
+	HealthURL      string      `json:"healthUrl" mapstructure:"healthUrl"`           // probed while ACTIVE and after a reload
+	// HealthFailureThreshold consecutive failed health probes before the ACTIVE component is restarted
+	HealthFailureThreshold int               `json:"healthFailureThreshold" mapstructure:"healthFailureThreshold"`
+	Reload                 ReloadSettings    `json:"reload" mapstructure:"reload"`
+	ContainerInstance      ContainerInstance `json:"container" mapstructure:"container"`
+
+	// Source drop-in file the component is declared in, empty for the main configuration
+	Source string `json:"-" mapstructure:"-"`
 }
 
 // TransportSettings transport configuration
 type TransportSettings struct {
-	Scheme string `json:"scheme"`
-	IP     string `json:"ip"`
-	Port   int    `json:"port"`
+	Scheme string `json:"scheme" mapstructure:"scheme"`
+	IP     string `json:"ip" mapstructure:"ip"`
+	Port   int    `json:"port" mapstructure:"port"`
+}
+
+// LifecycleSettings lifecycle timing configuration, intervals and timeouts are in milliseconds
+type LifecycleSettings struct {
+	HeartBeatInterval     int `json:"heartBeatInterval" mapstructure:"heartBeatInterval"`
+	StatusRefreshInterval int `json:"statusRefreshInterval" mapstructure:"statusRefreshInterval"`
+	DiscoveryInterval     int `json:"discoveryInterval" mapstructure:"discoveryInterval"`
+	ConfigPollInterval    int `json:"configPollInterval" mapstructure:"configPollInterval"`
+	// StateTimeouts max time a component may stay in a state (e.g. "STANDBY": 60000) before it is moved to FAILED
+	StateTimeouts map[string]int `json:"stateTimeouts" mapstructure:"stateTimeouts"`
+	// RestartDelay time a FAILED component waits before it is restarted, it stays FAILED when not set
+	RestartDelay int `json:"restartDelay" mapstructure:"restartDelay"`
+	// HistorySize number of state transitions kept per component
+	HistorySize int `json:"historySize" mapstructure:"historySize"`
+	// AuditFile file every state transition is appended to as a json line, none when empty
+	AuditFile string `json:"auditFile" mapstructure:"auditFile"`
+}
+
+// ReloadSettings how a running component applies a changed configuration
+type ReloadSettings struct {
+	// Method is one of signal, http or restart (default)
+	Method string `json:"method" mapstructure:"method"`
+	// Signal sent with the signal method, defaults to SIGHUP
+	Signal string `json:"signal" mapstructure:"signal"`
+	// URL admin endpoint called with the http method
+	URL        string `json:"url" mapstructure:"url"`
+	HTTPMethod string `json:"httpMethod" mapstructure:"httpMethod"`
+	// VerifyTimeout time in milliseconds the reloaded component has to pass its probes before it is rolled back
+	VerifyTimeout int `json:"verifyTimeout" mapstructure:"verifyTimeout"`
+}
+
+// GetVerifyTimeout returns the time the reloaded component has to become healthy
+func (rs ReloadSettings) GetVerifyTimeout() time.Duration {
+	if rs.VerifyTimeout <= 0 {
+		return DefaultReloadVerifyTimeout
+	}
+	return time.Duration(rs.VerifyTimeout) * time.Millisecond
+}
+
+// GetHealthFailureThreshold returns the number of consecutive failed health probes before the component is restarted
+func (mc ManagedComponent) GetHealthFailureThreshold() int {
+	if mc.HealthFailureThreshold <= 0 {
+		return DefaultHealthFailureThreshold
+	}
+	return mc.HealthFailureThreshold
+}
+
+// LogOutput log forwarding output, Name is the output plugin (e.g. forward, es, stdout)
+type LogOutput struct {
+	Name       string            `json:"name" mapstructure:"name"`
+	Match      string            `json:"match" mapstructure:"match"`
+	Properties map[string]string `json:"properties" mapstructure:"properties"`
+}
+
+// LoggingSettings agent logging
+type LoggingSettings struct {
+	Format string    `json:"format" mapstructure:"format"` // text or json
+	Level  string    `json:"level" mapstructure:"level"`   // global level and logger=level overrides, e.g. info,jsonclient=warn
+	Sinks  []LogSink `json:"sinks" mapstructure:"sinks"`   // destinations of the agent logs, stdout when empty
+	UTC    bool      `json:"utc" mapstructure:"utc"`       // timestamps in UTC instead of local time
+	Caller bool      `json:"caller" mapstructure:"caller"` // add file:line of the logging call
+}
+
+// LogSink destination of the agent logs
+type LogSink struct {
+	Type   string `json:"type" mapstructure:"type"`     // stdout, file, syslog or forward
+	Level  string `json:"level" mapstructure:"level"`   // most verbose level written to the sink
+	Format string `json:"format" mapstructure:"format"` // text or json, defaults to logging.format
+
+	Path       string `json:"path" mapstructure:"path"`             // file
+	MaxSize    int    `json:"maxSize" mapstructure:"maxSize"`       // file: megabytes before rotation
+	MaxBackups int    `json:"maxBackups" mapstructure:"maxBackups"` // file: rotated files kept
+
+	Network            string `json:"network" mapstructure:"network"` // syslog: empty for the local daemon, udp, tcp or tls
+	Address            string `json:"address" mapstructure:"address"` // syslog and forward: host:port
+	Tag                string `json:"tag" mapstructure:"tag"`
+	Facility           string `json:"facility" mapstructure:"facility"`
+	CAFile             string `json:"caFile" mapstructure:"caFile"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify" mapstructure:"insecureSkipVerify"`
+	Component          string `json:"component" mapstructure:"component"` // forward: managed component receiving the records
+}
+
+// ForwardPort returns the port a managed component listens on for agent logs,
+// when a forward sink targets it
+func (ls LoggingSettings) ForwardPort(mc ManagedComponent) (int, bool) {
+	for _, sink := range ls.Sinks {
+		if sink.Type == "forward" && sink.Component == mc.Name {
+			if mc.Port > 0 {
+				return mc.Port, true
+			}
+			return DefaultForwardPort, true
+		}
+	}
+	return 0, false
+}
+
+// StatusPolicy policy for aggregating managed component states into the container status
+type StatusPolicy struct {
+	// Type is one of worst-of (default), all-required or quorum
+	Type string `json:"type" mapstructure:"type"`
+	// Quorum min number of ACTIVE non-critical components under the quorum policy, defaults to a majority
+	Quorum int `json:"quorum" mapstructure:"quorum"`
 }
 
 // ContainerInstance container instance configuration
 type ContainerInstance struct {
-	Domain  string `json:"domain"`
-	Cluster string `json:"cluster"`
-	Zone    string `json:"zone"`
-	Node    string `json:"node"`
+	Domain  string `json:"domain" mapstructure:"domain"`
+	Cluster string `json:"cluster" mapstructure:"cluster"`
+	Zone    string `json:"zone" mapstructure:"zone"`
+	Node    string `json:"node" mapstructure:"node"`
 
-	DomainID  string `json:"domainId"`
-	ClusterID string `json:"clusterId"`
-	ZoneID    string `json:"zoneId"`
-	NodeID    string `json:"nodeId"`
+	DomainID  string `json:"domainId" mapstructure:"domainId"`
+	ClusterID string `json:"clusterId" mapstructure:"clusterId"`
+	ZoneID    string `json:"zoneId" mapstructure:"zoneId"`
+	NodeID    string `json:"nodeId" mapstructure:"nodeId"`
 
-	IP              string `json:"ip"`
-	RegistryContext string `json:"registryContext"`
+	IP              string `json:"ip" mapstructure:"ip"`
+	TmgcID          string `json:"tmgcId" mapstructure:"tmgcId"`
+	RegistryContext string `json:"registryContext" mapstructure:"registryContext"`
 }
 
 func (mc *ManagedComponent) Clone(copyFrom ManagedComponent) {
@@ -59,6 +213,189 @@ func (mc *ManagedComponent) Clone(copyFrom ManagedComponent) {
 	mc.Script = copyFrom.Script
 	mc.Service = copyFrom.Service
 	mc.Factory = copyFrom.Factory
-	// TODO
-	// mc.ContainerInstance = copyFrom.ContainerInstance
+	mc.DependsOn = append([]string(nil), copyFrom.DependsOn...)
+	mc.Critical = copyFrom.Critical
+	mc.Optional = copyFrom.Optional
+	mc.Upstreams = append([]string(nil), copyFrom.Upstreams...)
+	mc.Port = copyFrom.Port
+	mc.ConfigTemplate = copyFrom.ConfigTemplate
+	mc.ConfigFile = copyFrom.ConfigFile
+	mc.Outputs = append([]LogOutput(nil), copyFrom.Outputs...)
+	mc.HealthURL = copyFrom.HealthURL
+	mc.HealthFailureThreshold = copyFrom.HealthFailureThreshold
+	mc.Reload = copyFrom.Reload
+	mc.Source = copyFrom.Source
+	mc.ContainerInstance = copyFrom.ContainerInstance
+}
+
+// SetContainerInstance updates the identity of the container the component runs in
+func (mc *ManagedComponent) SetContainerInstance(ci ContainerInstance) {
+	mc.ContainerInstance = ci
+}
+
+// ContainerInstance returns the container identity known from configuration and host lookup,
+// registry ids are added once the container is registered
+func (cd ContainerDaemon) ContainerInstance() ContainerInstance {
+	return ContainerInstance{
+		Domain:  cd.Domain,
+		Cluster: cd.Cluster,
+		Zone:    cd.Zone,
+		Node:    cd.Node,
+		IP:      cd.IP,
+	}
+}
+
+// ResolveFactories sets the factory of the components that only declare their type,
+// from the factory names by component type
+func (cd *ContainerDaemon) ResolveFactories(types map[string]string) {
+	for i, mc := range cd.Components {
+		if mc.Factory == "" {
+			cd.Components[i].Factory = types[mc.Type]
+		}
+	}
+}
+
+// WithDefaults returns a copy of the configuration with defaults applied to unset fields
+func (cd ContainerDaemon) WithDefaults() ContainerDaemon {
+	resolved := cd
+	resolved.CacheDir = cd.GetCacheDir()
+	resolved.LogDir = cd.GetLogDir()
+	resolved.StateFile = cd.GetStateFile()
+	if resolved.StatusPolicy.Type == "" {
+		resolved.StatusPolicy.Type = "worst-of"
+	}
+	if resolved.TransportSettings.Scheme == "" {
+		resolved.TransportSettings.Scheme = "http"
+	}
+
+	ls := cd.Lifecycle
+	resolved.Lifecycle.HeartBeatInterval = milliseconds(ls.GetHeartBeatInterval())
+	resolved.Lifecycle.StatusRefreshInterval = milliseconds(ls.GetStatusRefreshInterval())
+	resolved.Lifecycle.DiscoveryInterval = milliseconds(ls.GetDiscoveryInterval())
+	resolved.Lifecycle.ConfigPollInterval = milliseconds(ls.GetConfigPollInterval())
+	resolved.Lifecycle.HistorySize = ls.GetHistorySize()
+
+	resolved.Components = make([]ManagedComponent, len(cd.Components))
+	for i, mc := range cd.Components {
+		if mc.Reload.Method == "" {
+			mc.Reload.Method = "restart"
+		}
+		if mc.Reload.Method == "signal" && mc.Reload.Signal == "" {
+			mc.Reload.Signal = "SIGHUP"
+		}
+		if mc.Reload.Method == "http" && mc.Reload.HTTPMethod == "" {
+			mc.Reload.HTTPMethod = "POST"
+		}
+		mc.Reload.VerifyTimeout = milliseconds(mc.Reload.GetVerifyTimeout())
+		mc.HealthFailureThreshold = mc.GetHealthFailureThreshold()
+		resolved.Components[i] = mc
+	}
+	return resolved
+}
+
+func milliseconds(d time.Duration) int {
+	return int(d / time.Millisecond)
+}
+
+// GetCacheDir returns directory for locally cached state, defaults to mlca under the temp dir
+func (cd ContainerDaemon) GetCacheDir() string {
+	if cd.CacheDir == "" {
+		return filepath.Join(os.TempDir(), "mlca")
+	}
+	return cd.CacheDir
+}
+
+// GetStateFile returns the file the agent state is persisted in, defaults to <name>.state.json in the cache dir
+func (cd ContainerDaemon) GetStateFile() string {
+	if cd.StateFile == "" {
+		return filepath.Join(cd.GetCacheDir(), cd.Name+".state.json")
+	}
+	return cd.StateFile
+}
+
+// GetLogDir returns directory the output of managed components is captured in
+func (cd ContainerDaemon) GetLogDir() string {
+	if cd.LogDir == "" {
+		return filepath.Join(cd.GetCacheDir(), "logs")
+	}
+	return cd.LogDir
+}
+
+// ComponentLogFile returns the file the output of a managed component is captured in
+func (cd ContainerDaemon) ComponentLogFile(name string) string {
+	return filepath.Join(cd.GetLogDir(), name+".log")
+}
+
+// AgentURL returns the url of the agent api of the container on the local host, e.g. http://127.0.0.1:21780/mashling
+func (cd ContainerDaemon) AgentURL() string {
+	ts := cd.TransportSettings
+	scheme := ts.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	// the agent listens on all interfaces
+	host := ts.IP
+	if host == "" || host == "0.0.0.0" {
+		host = "127.0.0.1"
+	}
+	return fmt.Sprintf("%s://%s/%s", scheme, net.JoinHostPort(host, strconv.Itoa(ts.Port)), cd.Name)
+}
+
+// GetHeartBeatInterval returns the lifecycle reconciliation interval
+func (ls LifecycleSettings) GetHeartBeatInterval() time.Duration {
+	if ls.HeartBeatInterval <= 0 {
+		return DefaultHeartBeatInterval
+	}
+	return time.Duration(ls.HeartBeatInterval) * time.Millisecond
+}
+
+// GetStatusRefreshInterval returns the interval for re-publishing status to the registry
+func (ls LifecycleSettings) GetStatusRefreshInterval() time.Duration {
+	if ls.StatusRefreshInterval <= 0 {
+		return DefaultStatusRefreshInterval
+	}
+	return time.Duration(ls.StatusRefreshInterval) * time.Millisecond
+}
+
+// GetDiscoveryInterval returns the interval for polling the registry for upstream changes
+func (ls LifecycleSettings) GetDiscoveryInterval() time.Duration {
+	if ls.DiscoveryInterval <= 0 {
+		return DefaultDiscoveryInterval
+	}
+	return time.Duration(ls.DiscoveryInterval) * time.Millisecond
+}
+
+// GetConfigPollInterval returns the interval for polling the cluster manager for newer configuration
+func (ls LifecycleSettings) GetConfigPollInterval() time.Duration {
+	if ls.ConfigPollInterval <= 0 {
+		return DefaultConfigPollInterval
+	}
+	return time.Duration(ls.ConfigPollInterval) * time.Millisecond
+}
+
+// GetHistorySize returns the number of state transitions kept per component
+func (ls LifecycleSettings) GetHistorySize() int {
+	if ls.HistorySize <= 0 {
+		return DefaultHistorySize
+	}
+	return ls.HistorySize
+}
+
+// GetStateTimeout returns the max time allowed in the given state, false if the state has no timeout
+func (ls LifecycleSettings) GetStateTimeout(state string) (time.Duration, bool) {
+	// viper lower cases map keys, so match state names case insensitively
+	for s, t := range ls.StateTimeouts {
+		if strings.EqualFold(s, state) && t > 0 {
+			return time.Duration(t) * time.Millisecond, true
+		}
+	}
+	return 0, false
+}
+
+// GetRestartDelay returns the time a FAILED component waits before it is restarted, false if it is not restarted
+func (ls LifecycleSettings) GetRestartDelay() (time.Duration, bool) {
+	if ls.RestartDelay <= 0 {
+		return 0, false
+	}
+	return time.Duration(ls.RestartDelay) * time.Millisecond, true
 }
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DependencyOrder returns the components ordered so that every component comes after the components
+// it depends on, components without a dependency between them keep their configuration order
+func DependencyOrder(components []ManagedComponent) ([]ManagedComponent, error) {
+	if cycle := findDependencyCycle(components); cycle != nil {
+		return nil, fmt.Errorf("dependency cycle %s", strings.Join(cycle, " -> "))
+	}
+
+	byName := make(map[string]ManagedComponent)
+	for _, mc := range components {
+		byName[mc.Name] = mc
+	}
+
+	ordered := make([]ManagedComponent, 0, len(components))
+	visited := make(map[string]bool)
+	var visit func(mc ManagedComponent)
+	visit = func(mc ManagedComponent) {
+		if visited[mc.Name] {
+			return
+		}
+		visited[mc.Name] = true
+		for _, dep := range mc.DependsOn {
+			if depMC, ok := byName[dep]; ok {
+				visit(depMC)
+			}
+		}
+		ordered = append(ordered, mc)
+	}
+	for _, mc := range components {
+		visit(mc)
+	}
+	return ordered, nil
+}
+
+// findDependencyCycle returns the component names forming a dependency cycle, nil if there is none
+func findDependencyCycle(components []ManagedComponent) []string {
+	deps := make(map[string][]string)
+	for _, mc := range components {
+		deps[mc.Name] = mc.DependsOn
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	marks := make(map[string]int)
+	var stack []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		marks[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range deps[name] {
+			if _, ok := deps[dep]; !ok || dep == name {
+				// unknown and self dependencies are reported separately
+				continue
+			}
+			switch marks[dep] {
+			case visiting:
+				for i, n := range stack {
+					if n == dep {
+						return append(append([]string(nil), stack[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		marks[name] = done
+		return nil
+	}
+
+	for _, mc := range components {
+		if marks[mc.Name] == unvisited {
+			if cycle := visit(mc.Name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
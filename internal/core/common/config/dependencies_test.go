@@ -0,0 +1,51 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDependencyOrder(t *testing.T) {
+	component := func(name string, dependsOn ...string) ManagedComponent {
+		return ManagedComponent{Name: name, DependsOn: dependsOn}
+	}
+
+	tests := []struct {
+		name       string
+		components []ManagedComponent
+		want       []string
+		wantErr    string
+	}{
+		{"empty", nil, []string{}, ""},
+		{"configuration order kept", []ManagedComponent{component("a"), component("b"), component("c")}, []string{"a", "b", "c"}, ""},
+		{"dependency first", []ManagedComponent{component("gw", "lfa"), component("lfa")}, []string{"lfa", "gw"}, ""},
+		{"chain", []ManagedComponent{component("a", "b"), component("b", "c"), component("c")}, []string{"c", "b", "a"}, ""},
+		{"diamond", []ManagedComponent{component("a", "b", "c"), component("b", "d"), component("c", "d"), component("d")}, []string{"d", "b", "c", "a"}, ""},
+		{"unknown dependency ignored", []ManagedComponent{component("a", "missing"), component("b")}, []string{"a", "b"}, ""},
+		{"self dependency ignored", []ManagedComponent{component("a", "a")}, []string{"a"}, ""},
+		{"cycle", []ManagedComponent{component("a", "b"), component("b", "a")}, nil, "dependency cycle a -> b -> a"},
+		{"cycle behind a dependency", []ManagedComponent{component("a", "b"), component("b", "c"), component("c", "b")}, nil, "dependency cycle b -> c -> b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, err := DependencyOrder(tt.components)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("DependencyOrder() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DependencyOrder() error = %v", err)
+			}
+			got := make([]string, 0, len(ordered))
+			for _, mc := range ordered {
+				got = append(got, mc.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DependencyOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
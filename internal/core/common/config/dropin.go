@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// dropInExtensions extensions of the files loaded from the components directory
+var dropInExtensions = map[string]bool{
+	".json": true,
+	".yaml": true,
+	".yml":  true,
+	".toml": true,
+}
+
+// ComponentsDirPath returns the directory the drop-in files are loaded from, a relative
+// ComponentsDir is resolved against baseDir. It is empty when there is no ComponentsDir.
+func (cd ContainerDaemon) ComponentsDirPath(baseDir string) string {
+	if cd.ComponentsDir == "" || filepath.IsAbs(cd.ComponentsDir) {
+		return cd.ComponentsDir
+	}
+	return filepath.Join(baseDir, cd.ComponentsDir)
+}
+
+// LoadDropIns merges the components declared in the files of ComponentsDir into Components.
+// The environment is applied to the configuration first, see ApplyEnvironment, so ComponentsDir
+// may be overridden or contain references; the merged components get the same overrides and
+// expansion from environ. A relative ComponentsDir is resolved against baseDir. Files are merged
+// in lexical order after the components of the main configuration, each file declares either a
+// components list or a single component. Components declared twice are reported with both of
+// their sources.
+func (cd *ContainerDaemon) LoadDropIns(baseDir string, environ []string) error {
+	dir := cd.ComponentsDirPath(baseDir)
+	if dir == "" {
+		return nil
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("unable to read componentsDir %s: %s", dir, err)
+	}
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		if !f.IsDir() && dropInExtensions[strings.ToLower(filepath.Ext(f.Name()))] && !strings.HasPrefix(f.Name(), ".") {
+			names = append(names, f.Name())
+		}
+	}
+	sort.Strings(names)
+
+	declared := make(map[string]string)
+	for _, mc := range cd.Components {
+		declared[mc.Name] = sourceName(mc.Source)
+	}
+
+	env := environMap(environ)
+	var errs ValidationErrors
+	for _, name := range names {
+		file := filepath.Join(dir, name)
+		components, err := loadDropIn(file)
+		if err != nil {
+			errs.add(file, "%s", err)
+			continue
+		}
+		for _, mc := range components {
+			mc.Source = file
+			if source, ok := declared[mc.Name]; ok {
+				errs.add(file, "component [%s] is already declared in %s", mc.Name, source)
+				continue
+			}
+			declared[mc.Name] = file
+			cd.Components = append(cd.Components, mc)
+			i := len(cd.Components) - 1
+			applyComponentEnvironment(&cd.Components[i], componentPath(cd.Components, i), env, &errs)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// loadDropIn loads the components declared in a drop-in file
+func loadDropIn(file string) ([]ManagedComponent, error) {
+	v := viper.New()
+	v.SetConfigFile(file)
+	err := v.ReadInConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if v.IsSet("components") {
+		dropIn := struct {
+			Components []ManagedComponent `mapstructure:"components"`
+		}{}
+		err = v.Unmarshal(&dropIn)
+		return dropIn.Components, err
+	}
+
+	mc := ManagedComponent{}
+	err = v.Unmarshal(&mc)
+	if err != nil {
+		return nil, err
+	}
+	if mc.Name == "" {
+		return nil, fmt.Errorf("declares neither a components list nor a named component")
+	}
+	return []ManagedComponent{mc}, nil
+}
+
+func sourceName(source string) string {
+	if source == "" {
+		return "the main configuration"
+	}
+	return source
+}
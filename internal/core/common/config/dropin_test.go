@@ -0,0 +1,120 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// dropInDir creates a components directory with the files, by name
+func dropInDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "conf.d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadDropIns(t *testing.T) {
+	dir := dropInDir(t, map[string]string{
+		"20-gateways.json": `{"components": [{"name": "TMG-Microgateway", "factory": "MashlingComponentFactory"}, {"name": "TMG-Canary"}]}`,
+		"10-lfa.yaml":      "name: TMG-LFA\nscript: startup_lfa.sh\n",
+		"notes.txt":        "not a drop-in",
+		".10-lfa.yaml.swp": "name: TMG-Swap",
+	})
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "old.json"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// a relative componentsDir is resolved against the directory of the main configuration
+	cd := ContainerDaemon{ComponentsDir: filepath.Base(dir), Components: []ManagedComponent{{Name: "TMG-Main"}}}
+	if err := cd.LoadDropIns(filepath.Dir(dir), nil); err != nil {
+		t.Fatalf("LoadDropIns() error = %v", err)
+	}
+
+	want := []struct{ name, source string }{
+		{"TMG-Main", ""},
+		{"TMG-LFA", filepath.Join(dir, "10-lfa.yaml")},
+		{"TMG-Microgateway", filepath.Join(dir, "20-gateways.json")},
+		{"TMG-Canary", filepath.Join(dir, "20-gateways.json")},
+	}
+	if len(cd.Components) != len(want) {
+		t.Fatalf("loaded %d components, want %d: %+v", len(cd.Components), len(want), cd.Components)
+	}
+	for i, w := range want {
+		if mc := cd.Components[i]; mc.Name != w.name || mc.Source != w.source {
+			t.Errorf("component %d = %s from %q, want %s from %q", i, mc.Name, mc.Source, w.name, w.source)
+		}
+	}
+	if cd.Components[1].Script != "startup_lfa.sh" || cd.Components[2].Factory != "MashlingComponentFactory" {
+		t.Errorf("drop-in fields were not loaded: %+v", cd.Components[1:3])
+	}
+}
+
+func TestLoadDropInsCollisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr []string
+	}{
+		{
+			name:    "drop-in redeclares the main configuration",
+			files:   map[string]string{"10-main.yaml": "name: TMG-Main\n"},
+			wantErr: []string{"10-main.yaml: component [TMG-Main] is already declared in the main configuration"},
+		},
+		{
+			name: "two drop-ins declare the same component",
+			files: map[string]string{
+				"10-lfa.json": `{"name": "TMG-LFA"}`,
+				"20-lfa.yaml": "components:\n  - name: TMG-LFA\n",
+			},
+			wantErr: []string{"20-lfa.yaml: component [TMG-LFA] is already declared in "},
+		},
+		{
+			name: "every problem is reported",
+			files: map[string]string{
+				"10-main.yaml":     "name: TMG-Main\n",
+				"20-unnamed.json":  `{"script": "startup_lfa.sh"}`,
+				"30-invalid.json":  `{"name": `,
+				"40-gateways.json": `{"components": [{"name": "TMG-Microgateway"}]}`,
+			},
+			wantErr: []string{
+				"10-main.yaml: component [TMG-Main] is already declared",
+				"20-unnamed.json: declares neither a components list nor a named component",
+				"30-invalid.json: ",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := dropInDir(t, tt.files)
+			defer os.RemoveAll(dir)
+
+			cd := ContainerDaemon{ComponentsDir: dir, Components: []ManagedComponent{{Name: "TMG-Main"}}}
+			err := cd.LoadDropIns("", nil)
+			errs, ok := err.(ValidationErrors)
+			if !ok || len(errs) != len(tt.wantErr) {
+				t.Fatalf("LoadDropIns() error = %v, want %d problem(s)", err, len(tt.wantErr))
+			}
+			for i, want := range tt.wantErr {
+				if got := errs[i].Error(); !strings.Contains(got, want) {
+					t.Errorf("problem %d = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+
+	cd := ContainerDaemon{ComponentsDir: "/nonexistent/conf.d"}
+	if err := cd.LoadDropIns("", nil); err == nil || !strings.Contains(err.Error(), "unable to read componentsDir /nonexistent/conf.d") {
+		t.Errorf("LoadDropIns() of a missing componentsDir error = %v", err)
+	}
+}
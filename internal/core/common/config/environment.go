@@ -0,0 +1,246 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix prefix of the environment variables overriding configuration fields
+const EnvPrefix = "MLCA"
+
+// envRefPattern matches the start of a ${VAR} or ${VAR:-default} reference
+var envRefPattern = regexp.MustCompile(`^\$\{([A-Za-z_][A-Za-z0-9_]*)(:-)?`)
+
+var nonAlphanumeric = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// ApplyEnvironment applies environment overrides to the configuration and then expands
+// ${VAR} and ${VAR:-default} references in all of its string values, see ExpandEnv.
+//
+// Every field can be overridden by a variable named after its json path, upper cased and
+// joined with underscores under the MLCA prefix, e.g. MLCA_CLUSTER, MLCA_INBOXES_REGISTRY,
+// MLCA_TRANSPORTSETTINGS_PORT or MLCA_LIFECYCLE_STATETIMEOUTS_STANDBY. Component fields are
+// addressed by the component name with every non alphanumeric character replaced by an
+// underscore, e.g. MLCA_COMPONENTS_TMG_MICROGATEWAY_SCRIPT. Lists of strings are comma separated.
+func ApplyEnvironment(cd *ContainerDaemon, environ []string) error {
+	env := environMap(environ)
+
+	var errs ValidationErrors
+	applyOverrides(reflect.ValueOf(cd).Elem(), EnvPrefix, "", env, &errs)
+	expandStrings(reflect.ValueOf(cd).Elem(), env)
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// applyComponentEnvironment applies the overrides and expansion of ApplyEnvironment to a component
+// merged into the configuration after it was applied, path is the path of the component
+func applyComponentEnvironment(mc *ManagedComponent, path string, env map[string]string, errs *ValidationErrors) {
+	applyOverrides(reflect.ValueOf(mc).Elem(), EnvPrefix+"_COMPONENTS_"+envSegment(mc.Name), path, env, errs)
+	expandStrings(reflect.ValueOf(mc).Elem(), env)
+}
+
+func environMap(environ []string) map[string]string {
+	env := make(map[string]string)
+	for _, kv := range environ {
+		if i := strings.Index(kv, "="); i > 0 {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+	return env
+}
+
+func envSegment(s string) string {
+	return strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToUpper(s), "_"), "_")
+}
+
+func fieldName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" {
+		name = f.Name
+	}
+	return name
+}
+
+func applyOverrides(v reflect.Value, envName, path string, env map[string]string, errs *ValidationErrors) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" || f.Tag.Get("json") == "-" {
+				continue
+			}
+			name := fieldName(f)
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+			applyOverrides(v.Field(i), envName+"_"+envSegment(name), fieldPath, env, errs)
+		}
+
+	case reflect.Slice:
+		if components, ok := v.Addr().Interface().(*[]ManagedComponent); ok {
+			for i := range *components {
+				mc := &(*components)[i]
+				applyOverrides(reflect.ValueOf(mc).Elem(), envName+"_"+envSegment(mc.Name), fmt.Sprintf("%s[%d]", path, i), env, errs)
+			}
+			return
+		}
+		if value, ok := env[envName]; ok && v.Type().Elem().Kind() == reflect.String {
+			list := []string{}
+			for _, s := range strings.Split(value, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					list = append(list, s)
+				}
+			}
+			v.Set(reflect.ValueOf(list))
+		}
+
+	case reflect.Map:
+		// existing keys are matched case insensitively, new keys are added in lower case
+		keyPrefix := envName + "_"
+		names := make([]string, 0)
+		for name := range env {
+			if strings.HasPrefix(name, keyPrefix) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			key := strings.ToLower(strings.TrimPrefix(name, keyPrefix))
+			for _, k := range v.MapKeys() {
+				if envSegment(k.String()) == strings.TrimPrefix(name, keyPrefix) {
+					key = k.String()
+				}
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if !setScalar(elem, env[name]) {
+				errs.add(path+"."+key, "invalid value [%s] in %s", env[name], name)
+				continue
+			}
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			v.SetMapIndex(reflect.ValueOf(key), elem)
+		}
+
+	default:
+		if value, ok := env[envName]; ok {
+			if !setScalar(v, value) {
+				errs.add(path, "invalid value [%s] in %s", value, envName)
+			}
+		}
+	}
+}
+
+func setScalar(v reflect.Value, value string) bool {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int:
+		i, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return false
+		}
+		v.SetInt(int64(i))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return false
+		}
+		v.SetBool(b)
+	default:
+		return false
+	}
+	return true
+}
+
+// expandStrings expands environment references in every string reachable from v
+func expandStrings(v reflect.Value, env map[string]string) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(ExpandEnv(v.String(), env))
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				expandStrings(v.Field(i), env)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			expandStrings(v.Index(i), env)
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for _, k := range v.MapKeys() {
+			v.SetMapIndex(k, reflect.ValueOf(ExpandEnv(v.MapIndex(k).String(), env)))
+		}
+	}
+}
+
+// ExpandEnv replaces ${VAR} with the value of VAR and ${VAR:-default} with the value of VAR,
+// or default when VAR is unset or empty. Defaults may contain references, e.g. ${A:-${B:-b}}.
+// $${ is written as a literal ${, so a script keeps references meant for its own shell.
+// References that are not closed or do not name a variable are kept as they are.
+func ExpandEnv(s string, env map[string]string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			b.WriteString("${")
+			i += 3
+			continue
+		}
+		if value, n, ok := expandRef(s[i:], env); ok {
+			b.WriteString(value)
+			i += n
+			continue
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// expandRef expands the reference s starts with, returning its value and length
+func expandRef(s string, env map[string]string) (string, int, bool) {
+	m := envRefPattern.FindStringSubmatch(s)
+	if m == nil {
+		return "", 0, false
+	}
+	start := len(m[0])
+	if m[2] == "" {
+		if start < len(s) && s[start] == '}' {
+			return env[m[1]], start + 1, true
+		}
+		return "", 0, false
+	}
+
+	// the default ends at the brace closing the reference, after the ones of nested references
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			depth++
+			i += 2
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}' && depth > 0:
+			depth--
+		case s[i] == '}':
+			if value := env[m[1]]; value != "" {
+				return value, i + 1, true
+			}
+			return ExpandEnv(s[start:i], env), i + 1, true
+		}
+	}
+	return "", 0, false
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{"A": "x", "B": "y", "EMPTY": ""}
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{"${A}", "x"},
+		{"${A}/${B}", "x/y"},
+		{"${UNSET}", ""},
+		{"${EMPTY}", ""},
+		{"${A:-default}", "x"},
+		{"${UNSET:-default}", "default"},
+		{"${EMPTY:-default}", "default"},
+		{"${UNSET:-}", ""},
+		{"${UNSET:-/opt/lfa}/startup_lfa.sh", "/opt/lfa/startup_lfa.sh"},
+		{"${UNSET:-Mashery Local 5}", "Mashery Local 5"},
+		{"${A:-${B}}", "x"},
+		{"${UNSET:-${B}}", "y"},
+		{"${UNSET:-${OTHER:-z}}-", "z-"},
+		{"${UNSET:-a${B}b}", "ayb"},
+		{"$${A}", "${A}"},
+		{"sh -c 'echo $${HOME}'", "sh -c 'echo ${HOME}'"},
+		{"${UNSET:-$${B}}", "${B}"},
+		{"$A ${A", "$A ${A"},
+		{"${1A}", "${1A}"},
+		{"${UNSET:-a}}", "a}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := ExpandEnv(tt.in, env); got != tt.want {
+				t.Errorf("ExpandEnv(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyEnvironment(t *testing.T) {
+	base := func() ContainerDaemon {
+		return ContainerDaemon{
+			Name:       "mashling",
+			Cluster:    "${TMG_CLUSTER_NAME:-Mashery Local 5}",
+			Inboxes:    map[string]string{"registry": "http://${REGISTRY_HOST:-tmgc-cass}:21180"},
+			Lifecycle:  LifecycleSettings{StateTimeouts: map[string]int{"standby": 60000}},
+			Components: []ManagedComponent{{Name: "TMG-LFA", Script: "startup_lfa.sh", Reload: ReloadSettings{Method: "restart"}}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		environ []string
+		check   func(cd ContainerDaemon) interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{"default", nil, func(cd ContainerDaemon) interface{} { return cd.Cluster }, "Mashery Local 5", false},
+		{"expanded", []string{"TMG_CLUSTER_NAME=Local"}, func(cd ContainerDaemon) interface{} { return cd.Cluster }, "Local", false},
+		{"override wins", []string{"TMG_CLUSTER_NAME=Local", "MLCA_CLUSTER=Remote"}, func(cd ContainerDaemon) interface{} { return cd.Cluster }, "Remote", false},
+		{"override expanded", []string{"MLCA_CLUSTER=${ZONE:-z}-cluster"}, func(cd ContainerDaemon) interface{} { return cd.Cluster }, "z-cluster", false},
+		{"map value expanded", []string{"REGISTRY_HOST=cass"}, func(cd ContainerDaemon) interface{} { return cd.Inboxes["registry"] }, "http://cass:21180", false},
+		{"map key added", []string{"MLCA_INBOXES_MANAGER=http://cm:21180"}, func(cd ContainerDaemon) interface{} { return cd.Inboxes["manager"] }, "http://cm:21180", false},
+		{"int", []string{"MLCA_TRANSPORTSETTINGS_PORT=21781"}, func(cd ContainerDaemon) interface{} { return cd.TransportSettings.Port }, 21781, false},
+		{"map key matched", []string{"MLCA_LIFECYCLE_STATETIMEOUTS_STANDBY=1000"}, func(cd ContainerDaemon) interface{} { return cd.Lifecycle.StateTimeouts }, map[string]int{"standby": 1000}, false},
+		{"component by name", []string{"MLCA_COMPONENTS_TMG_LFA_RELOAD_METHOD=signal"}, func(cd ContainerDaemon) interface{} { return cd.Components[0].Reload.Method }, "signal", false},
+		{"component bool", []string{"MLCA_COMPONENTS_TMG_LFA_CRITICAL=true"}, func(cd ContainerDaemon) interface{} { return cd.Components[0].Critical }, true, false},
+		{"list", []string{"MLCA_COMPONENTS_TMG_LFA_DEPENDSON=a, b,"}, func(cd ContainerDaemon) interface{} { return cd.Components[0].DependsOn }, []string{"a", "b"}, false},
+		{"escaped script", []string{"MLCA_COMPONENTS_TMG_LFA_SCRIPT=run $${HOME}"}, func(cd ContainerDaemon) interface{} { return cd.Components[0].Script }, "run ${HOME}", false},
+		{"invalid int", []string{"MLCA_TRANSPORTSETTINGS_PORT=http"}, nil, nil, true},
+		{"invalid bool", []string{"MLCA_COMPONENTS_TMG_LFA_CRITICAL=maybe"}, nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cd := base()
+			err := ApplyEnvironment(&cd, tt.environ)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ApplyEnvironment() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyEnvironment() error = %v", err)
+			}
+			if got := tt.check(cd); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplyEnvironment() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
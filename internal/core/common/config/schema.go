@@ -0,0 +1,111 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// SchemaID identifier of the published container configuration schema
+const SchemaID = "https://github.com/rameshpolishetti/mlca/schema/config.schema.json"
+
+// schemaOverrides schema properties that can not be derived from the field types, keyed by json path
+var schemaOverrides = map[string]map[string]interface{}{
+	"":                                    {"required": []string{"name", "componentType", "cluster", "zone", "inboxes", "transportSettings"}},
+	"name":                                {"description": "container name, used in the agent api paths"},
+	"componentType":                       {"description": "registry collection the container registers in, e.g. trafficmanagers"},
+	"port":                                {"type": []string{"string", "integer"}, "description": "port of the containerized application"},
+	"inboxes":                             {"required": []string{"registry"}, "description": "urls of the registry, manager and agent"},
+	"inboxes.*":                           {"format": "uri"},
+	"transportSettings":                   {"required": []string{"port"}},
+	"transportSettings.scheme":            {"enum": []string{"http", "https"}},
+	"transportSettings.port":              {"minimum": 1, "maximum": 65535, "description": "port of the agent api"},
+	"lifecycle":                           {"description": "lifecycle timing, intervals and timeouts are in milliseconds"},
+	"lifecycle.stateTimeouts":             {"description": "max time a component may stay in a state before it is ACTIVE, it is moved to FAILED when exceeded", "propertyNames": map[string]interface{}{"pattern": "(?i)^(" + strings.Join(lifecycleStates, "|") + ")$"}},
+	"lifecycle.restartDelay":              {"description": "time a FAILED component waits before it is restarted, it stays FAILED when not set"},
+	"lifecycle.historySize":               {"description": "number of state transitions kept per component (default 100)"},
+	"lifecycle.auditFile":                 {"description": "file every state transition is appended to as a json line"},
+	"statusPolicy.type":                   {"enum": []string{"worst-of", "all-required", "quorum"}},
+	"logging.format":                      {"enum": []string{"text", "json"}, "description": "format of the agent log lines"},
+	"logging.level":                       {"description": "global log level followed by comma separated logger=level overrides, e.g. info,jsonclient=warn"},
+	"logging.sinks.*":                     {"required": []string{"type"}},
+	"logging.sinks.*.type":                {"enum": []string{"stdout", "file", "syslog", "forward"}},
+	"logging.sinks.*.level":               {"enum": []string{"panic", "fatal", "error", "warn", "warning", "info", "debug", "trace"}},
+	"logging.sinks.*.format":              {"enum": []string{"text", "json"}},
+	"logging.sinks.*.network":             {"enum": []string{"", "udp", "tcp", "tls"}, "description": "syslog transport, empty for the local daemon"},
+	"logging.sinks.*.component":           {"description": "forward sink target, e.g. TMG-LFA, which then receives the agent logs on its port (default 24224)"},
+	"components.*":                        {"required": []string{"name", "qualifier", "script"}},
+	"components.*.script":                 {"minLength": 1, "description": "command line starting the component, ${VAR:-default} references are expanded, $${ is kept as a literal ${"},
+	"components.*.type":                   {"description": "component type, e.g. Microgateway or Log, selecting the factory when factory is not set"},
+	"components.*.factory":                {"description": "registered component factory, e.g. MashlingComponentFactory or FluentBitComponentFactory, defaults to the factory of type"},
+	"components.*.dependsOn":              {"description": "components that must be ACTIVE before this one resolves"},
+	"components.*.critical":               {"description": "must be ACTIVE for the container to be ACTIVE under the all-required and quorum policies, only a critical component fails the container"},
+	"components.*.optional":               {"description": "never affects the container status"},
+	"components.*.upstreams":              {"description": "componentTypes discovered through the registry"},
+	"components.*.port":                   {"minimum": 1, "maximum": 65535},
+	"components.*.healthUrl":              {"format": "uri"},
+	"components.*.healthFailureThreshold": {"minimum": 0, "description": "consecutive failed health probes before the ACTIVE component is restarted (default 3)"},
+	"components.*.reload.method":          {"enum": []string{"restart", "signal", "http"}},
+	"components.*.reload.url":             {"format": "uri"},
+	"components.*.outputs.*":              {"required": []string{"name"}},
+	"components.*.container":              {"description": "populated by the agent from the registry registration"},
+	"components.*.outputs.*.name":         {"description": "fluent-bit output plugin, e.g. forward, es or stdout"},
+}
+
+//go:generate go run github.com/rameshpolishetti/mlca schema -o ../../../../schema/config.schema.json
+
+// JSONSchema returns the JSON Schema of the container configuration, generated from the config types
+func JSONSchema() ([]byte, error) {
+	schema := typeSchema(reflect.TypeOf(ContainerDaemon{}), "")
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["$id"] = SchemaID
+	schema["title"] = "mlca container configuration"
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func typeSchema(t reflect.Type, path string) map[string]interface{} {
+	schema := map[string]interface{}{}
+
+	switch t.Kind() {
+	case reflect.String:
+		schema["type"] = "string"
+	case reflect.Int, reflect.Int64:
+		schema["type"] = "integer"
+	case reflect.Bool:
+		schema["type"] = "boolean"
+	case reflect.Slice:
+		schema["type"] = "array"
+		schema["items"] = typeSchema(t.Elem(), childPath(path, "*"))
+	case reflect.Map:
+		schema["type"] = "object"
+		schema["additionalProperties"] = typeSchema(t.Elem(), childPath(path, "*"))
+	case reflect.Struct:
+		schema["type"] = "object"
+		schema["additionalProperties"] = false
+		properties := map[string]interface{}{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name := strings.Split(f.Tag.Get("mapstructure"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			properties[name] = typeSchema(f.Type, childPath(path, name))
+		}
+		schema["properties"] = properties
+	}
+
+	for k, v := range schemaOverrides[path] {
+		schema[k] = v
+	}
+	return schema
+}
+
+func childPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,374 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rameshpolishetti/mlca/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// lifecycleStates states a stateTimeouts entry may refer to, the states a component passes through before it is ACTIVE
+var lifecycleStates = []string{"UNKNOWN", "UNSATISFIED", "RESOLVED", "STANDBY"}
+
+// untimedStates states that have no stateTimeouts entry, with the setting limiting them instead
+var untimedStates = map[string]string{
+	"ACTIVE": "ACTIVE is not left on a timeout",
+	"RELOAD": "the reload of a component is limited by its reload.verifyTimeout",
+	"FAILED": "the restart of a FAILED component is delayed by lifecycle.restartDelay",
+}
+
+// FieldError validation problem of a configuration field
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (fe FieldError) Error() string {
+	return fe.Path + ": " + fe.Message
+}
+
+// ValidationErrors all problems found in a configuration
+type ValidationErrors []FieldError
+
+func (ve ValidationErrors) Error() string {
+	lines := make([]string, 0, len(ve))
+	for _, fe := range ve {
+		lines = append(lines, fe.Error())
+	}
+	return fmt.Sprintf("%d configuration problem(s):\n  %s", len(ve), strings.Join(lines, "\n  "))
+}
+
+func (ve *ValidationErrors) add(path, format string, args ...interface{}) {
+	*ve = append(*ve, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the container configuration and reports all problems at once, factories are the
+// names of the known component factories and types their names by the component type selecting them
+func (cd ContainerDaemon) Validate(factories []string, types map[string]string) error {
+	var errs ValidationErrors
+
+	for _, f := range []struct{ path, value string }{
+		{"name", cd.Name},
+		{"componentType", cd.ComponentType},
+		{"cluster", cd.Cluster},
+		{"zone", cd.Zone},
+	} {
+		if strings.TrimSpace(f.value) == "" {
+			errs.add(f.path, "is required")
+		}
+	}
+
+	if cd.Port != "" {
+		port, err := strconv.Atoi(cd.Port)
+		if err != nil {
+			errs.add("port", "[%s] is not a number", cd.Port)
+		} else {
+			validatePort(&errs, "port", port)
+		}
+	}
+
+	if cd.Inboxes["registry"] == "" {
+		errs.add("inboxes.registry", "is required")
+	}
+	for _, name := range sortedKeys(cd.Inboxes) {
+		if inbox := cd.Inboxes[name]; inbox != "" {
+			validateURL(&errs, "inboxes."+name, inbox)
+		}
+	}
+
+	if s := cd.TransportSettings.Scheme; s != "" && s != "http" && s != "https" {
+		errs.add("transportSettings.scheme", "[%s] must be http or https", s)
+	}
+	if cd.TransportSettings.Port == 0 {
+		errs.add("transportSettings.port", "is required")
+	} else {
+		validatePort(&errs, "transportSettings.port", cd.TransportSettings.Port)
+	}
+
+	validateLifecycle(&errs, cd.Lifecycle)
+
+	switch cd.StatusPolicy.Type {
+	case "", "worst-of", "all-required", "quorum":
+	default:
+		errs.add("statusPolicy.type", "[%s] must be one of worst-of, all-required, quorum", cd.StatusPolicy.Type)
+	}
+	if cd.StatusPolicy.Quorum < 0 {
+		errs.add("statusPolicy.quorum", "must not be negative")
+	} else if cd.StatusPolicy.Type == "quorum" {
+		// the quorum counts the ACTIVE components that are neither critical nor optional
+		others := 0
+		for _, mc := range cd.Components {
+			if !mc.Critical && !mc.Optional {
+				others++
+			}
+		}
+		if cd.StatusPolicy.Quorum > others {
+			errs.add("statusPolicy.quorum", "[%d] exceeds the %d components that are neither critical nor optional", cd.StatusPolicy.Quorum, others)
+		}
+	}
+
+	validateLogging(&errs, cd.Logging, cd.Components)
+
+	validateComponents(&errs, cd.Components, factories, types)
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateLifecycle(errs *ValidationErrors, ls LifecycleSettings) {
+	for _, f := range []struct {
+		path  string
+		value int
+	}{
+		{"lifecycle.heartBeatInterval", ls.HeartBeatInterval},
+		{"lifecycle.statusRefreshInterval", ls.StatusRefreshInterval},
+		{"lifecycle.discoveryInterval", ls.DiscoveryInterval},
+		{"lifecycle.configPollInterval", ls.ConfigPollInterval},
+		{"lifecycle.restartDelay", ls.RestartDelay},
+		{"lifecycle.historySize", ls.HistorySize},
+	} {
+		if f.value < 0 {
+			errs.add(f.path, "must not be negative")
+		}
+	}
+
+	states := make([]string, 0, len(ls.StateTimeouts))
+	for state := range ls.StateTimeouts {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	for _, state := range states {
+		timeout := ls.StateTimeouts[state]
+		path := "lifecycle.stateTimeouts." + state
+		if reason, ok := untimedStates[strings.ToUpper(state)]; ok {
+			errs.add(path, "is not allowed, %s", reason)
+		} else if !containsFold(lifecycleStates, state) {
+			errs.add(path, "unknown state, must be one of %s", strings.Join(lifecycleStates, ", "))
+		}
+		if timeout < 0 {
+			errs.add(path, "must not be negative")
+		}
+	}
+}
+
+func validateComponents(errs *ValidationErrors, components []ManagedComponent, factories []string, types map[string]string) {
+	names := make(map[string]int)
+	qualifiers := make(map[string]int)
+
+	for i, mc := range components {
+		path := componentPath(components, i)
+
+		if strings.TrimSpace(mc.Name) == "" {
+			errs.add(path+".name", "is required")
+		} else if j, ok := names[mc.Name]; ok {
+			errs.add(path+".name", "[%s] is already used by %s", mc.Name, componentPath(components, j))
+		} else {
+			names[mc.Name] = i
+		}
+
+		if strings.TrimSpace(mc.Qualifier) == "" {
+			errs.add(path+".qualifier", "is required")
+		} else if j, ok := qualifiers[mc.Qualifier]; ok {
+			errs.add(path+".qualifier", "[%s] is already used by %s", mc.Qualifier, componentPath(components, j))
+		} else {
+			qualifiers[mc.Qualifier] = i
+		}
+
+		if strings.TrimSpace(mc.Script) == "" {
+			errs.add(path+".script", "is required")
+		}
+
+		if mc.Type != "" {
+			if factory, ok := types[mc.Type]; !ok {
+				errs.add(path+".type", "unknown type [%s], must be one of %s", mc.Type, strings.Join(sortedKeys(types), ", "))
+			} else if mc.Factory != "" && mc.Factory != factory {
+				errs.add(path+".type", "[%s] is created by %s, not by factory %s", mc.Type, factory, mc.Factory)
+			}
+		}
+		if mc.Factory == "" {
+			errs.add(path+".factory", "is required unless type is one of %s", strings.Join(sortedKeys(types), ", "))
+		} else if !contains(factories, mc.Factory) {
+			errs.add(path+".factory", "unknown factory [%s], must be one of %s", mc.Factory, strings.Join(factories, ", "))
+		}
+
+		if mc.Port != 0 {
+			validatePort(errs, path+".port", mc.Port)
+		}
+		if mc.Critical && mc.Optional {
+			errs.add(path+".optional", "a component can not be both critical and optional")
+		}
+		if mc.HealthURL != "" {
+			validateURL(errs, path+".healthUrl", mc.HealthURL)
+		}
+		if mc.HealthFailureThreshold < 0 {
+			errs.add(path+".healthFailureThreshold", "must not be negative")
+		}
+
+		switch mc.Reload.Method {
+		case "", "restart", "signal":
+		case "http":
+			if mc.Reload.URL == "" {
+				errs.add(path+".reload.url", "is required for the http reload method")
+			} else {
+				validateURL(errs, path+".reload.url", mc.Reload.URL)
+			}
+		default:
+			errs.add(path+".reload.method", "[%s] must be one of restart, signal, http", mc.Reload.Method)
+		}
+		if mc.Reload.VerifyTimeout < 0 {
+			errs.add(path+".reload.verifyTimeout", "must not be negative")
+		}
+
+		for j, o := range mc.Outputs {
+			if o.Name == "" {
+				errs.add(fmt.Sprintf("%s.outputs[%d].name", path, j), "is required")
+			}
+		}
+	}
+
+	for i, mc := range components {
+		for j, dep := range mc.DependsOn {
+			depPath := fmt.Sprintf("%s.dependsOn[%d]", componentPath(components, i), j)
+			if dep == mc.Name {
+				errs.add(depPath, "a component can not depend on itself")
+			} else if _, ok := names[dep]; !ok {
+				errs.add(depPath, "unknown component [%s]", dep)
+			}
+		}
+	}
+
+	if cycle := findDependencyCycle(components); cycle != nil {
+		errs.add(componentPath(components, names[cycle[0]])+".dependsOn", "dependency cycle %s", strings.Join(cycle, " -> "))
+	}
+}
+
+func hasComponent(components []ManagedComponent, name string) bool {
+	for _, mc := range components {
+		if mc.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// componentPath returns the field path of a component, naming the drop-in file it is declared in
+func componentPath(components []ManagedComponent, i int) string {
+	if components[i].Source != "" {
+		return fmt.Sprintf("components[%d](%s)", i, components[i].Source)
+	}
+	return fmt.Sprintf("components[%d]", i)
+}
+
+func validateLogging(errs *ValidationErrors, ls LoggingSettings, components []ManagedComponent) {
+	switch ls.Format {
+	case "", "text", "json":
+	default:
+		errs.add("logging.format", "[%s] must be one of text, json", ls.Format)
+	}
+	if _, _, err := logger.ParseLevels(ls.Level); err != nil {
+		errs.add("logging.level", "%s", err)
+	}
+
+	for i, sink := range ls.Sinks {
+		path := fmt.Sprintf("logging.sinks[%d]", i)
+		if sink.Level != "" {
+			if _, err := logrus.ParseLevel(sink.Level); err != nil {
+				errs.add(path+".level", "%s", err)
+			}
+		}
+		switch sink.Format {
+		case "", "text", "json":
+		default:
+			errs.add(path+".format", "[%s] must be one of text, json", sink.Format)
+		}
+
+		switch sink.Type {
+		case "stdout":
+		case "file":
+			if sink.Path == "" {
+				errs.add(path+".path", "is required for file sinks")
+			}
+			if sink.MaxSize < 0 {
+				errs.add(path+".maxSize", "must not be negative")
+			}
+			if sink.MaxBackups < 0 {
+				errs.add(path+".maxBackups", "must not be negative")
+			}
+		case "syslog":
+			switch sink.Network {
+			case "":
+			case "udp", "tcp", "tls":
+				if sink.Address == "" {
+					errs.add(path+".address", "is required for %s syslog sinks", sink.Network)
+				}
+			default:
+				errs.add(path+".network", "[%s] must be empty for the local daemon or one of udp, tcp, tls", sink.Network)
+			}
+		case "forward":
+			if sink.Address == "" && sink.Component == "" {
+				errs.add(path+".address", "address or component is required for forward sinks")
+			}
+			if sink.Address != "" && sink.Component != "" {
+				errs.add(path+".component", "can not be combined with address")
+			} else if sink.Component != "" && !hasComponent(components, sink.Component) {
+				errs.add(path+".component", "unknown component [%s]", sink.Component)
+			}
+		case "":
+			errs.add(path+".type", "is required")
+		default:
+			errs.add(path+".type", "[%s] must be one of stdout, file, syslog, forward", sink.Type)
+		}
+	}
+}
+
+func validatePort(errs *ValidationErrors, path string, port int) {
+	if port < 1 || port > 65535 {
+		errs.add(path, "%d is out of range 1-65535", port)
+	}
+}
+
+func validateURL(errs *ValidationErrors, path, value string) {
+	u, err := url.Parse(value)
+	if err != nil {
+		errs.add(path, "[%s] is not a valid url: %s", value, err)
+		return
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		errs.add(path, "[%s] must be an http or https url", value)
+	} else if u.Host == "" {
+		errs.add(path, "[%s] has no host", value)
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, l := range list {
+		if strings.EqualFold(l, s) {
+			return true
+		}
+	}
+	return false
+}
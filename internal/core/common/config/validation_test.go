@@ -0,0 +1,147 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	testFactories = []string{"FluentBitComponentFactory", "MashlingComponentFactory"}
+	testTypes     = map[string]string{"Log": "FluentBitComponentFactory", "Microgateway": "MashlingComponentFactory"}
+)
+
+func validContainer() ContainerDaemon {
+	return ContainerDaemon{
+		Name:              "mashling",
+		ComponentType:     "trafficmanagers",
+		Cluster:           "cluster",
+		Zone:              "zone",
+		Inboxes:           map[string]string{"registry": "http://registry:21180"},
+		TransportSettings: TransportSettings{Port: 21780},
+		Components: []ManagedComponent{
+			{Name: "gw", Qualifier: "microgateway", Script: "mashling-gateway", Factory: "MashlingComponentFactory", Critical: true},
+			{Name: "lfa", Qualifier: "lfa", Script: "startup_lfa.sh", Type: "Log", Factory: "FluentBitComponentFactory"},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cd *ContainerDaemon)
+		want   []string
+	}{
+		{"valid", func(cd *ContainerDaemon) {}, nil},
+		{"required fields", func(cd *ContainerDaemon) {
+			cd.Name = ""
+			cd.Inboxes = nil
+			cd.TransportSettings.Port = 0
+		}, []string{"name", "inboxes.registry", "transportSettings.port"}},
+		{"invalid values", func(cd *ContainerDaemon) {
+			cd.Port = "http"
+			cd.Inboxes["manager"] = "not a url"
+			cd.TransportSettings.Scheme = "ftp"
+			cd.Lifecycle.HeartBeatInterval = -1
+			cd.Lifecycle.StateTimeouts = map[string]int{"standby": 1000, "waiting": 1000}
+		}, []string{"port", "inboxes.manager", "transportSettings.scheme", "lifecycle.heartBeatInterval", "lifecycle.stateTimeouts.waiting"}},
+		{"status policy", func(cd *ContainerDaemon) {
+			cd.StatusPolicy.Type = "best-of"
+		}, []string{"statusPolicy.type"}},
+		{"quorum within the non-critical components", func(cd *ContainerDaemon) {
+			cd.StatusPolicy = StatusPolicy{Type: "quorum", Quorum: 1}
+		}, nil},
+		{"quorum exceeding the non-critical components", func(cd *ContainerDaemon) {
+			cd.StatusPolicy = StatusPolicy{Type: "quorum", Quorum: 2}
+		}, []string{"statusPolicy.quorum"}},
+		{"duplicate component", func(cd *ContainerDaemon) {
+			cd.Components[1].Name = "gw"
+			cd.Components[1].Qualifier = "microgateway"
+		}, []string{"components[1].name", "components[1].qualifier"}},
+		{"factory from type", func(cd *ContainerDaemon) {
+			cd.Components[1].Factory = ""
+			cd.ResolveFactories(testTypes)
+		}, nil},
+		{"unknown type and factory", func(cd *ContainerDaemon) {
+			cd.Components[0].Factory = "NginxComponentFactory"
+			cd.Components[1].Type = "Logs"
+			cd.Components[1].Factory = ""
+			cd.ResolveFactories(testTypes)
+		}, []string{"components[0].factory", "components[1].type", "components[1].factory"}},
+		{"type of another factory", func(cd *ContainerDaemon) {
+			cd.Components[1].Type = "Microgateway"
+		}, []string{"components[1].type"}},
+		{"component fields", func(cd *ContainerDaemon) {
+			cd.Components[0].Script = " "
+			cd.Components[0].Optional = true
+			cd.Components[1].HealthURL = "localhost"
+			cd.Components[1].Reload = ReloadSettings{Method: "http"}
+		}, []string{"components[0].script", "components[0].optional", "components[1].healthUrl", "components[1].reload.url"}},
+		{"dependencies", func(cd *ContainerDaemon) {
+			cd.Components[0].DependsOn = []string{"lfa", "gw", "missing"}
+			cd.Components[1].DependsOn = []string{"gw"}
+		}, []string{"components[0].dependsOn[1]", "components[0].dependsOn[2]", "components[0].dependsOn"}},
+		{"drop-in source in path", func(cd *ContainerDaemon) {
+			cd.Components[1].Source = "conf.d/lfa.json"
+			cd.Components[1].Qualifier = ""
+		}, []string{"components[1](conf.d/lfa.json).qualifier"}},
+		{"logging", func(cd *ContainerDaemon) {
+			cd.Logging = LoggingSettings{
+				Level: "info,=debug",
+				Sinks: []LogSink{
+					{Type: "file"},
+					{Type: "forward", Component: "fluentd"},
+					{Type: "kafka"},
+				},
+			}
+		}, []string{"logging.level", "logging.sinks[0].path", "logging.sinks[1].component", "logging.sinks[2].type"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cd := validContainer()
+			tt.modify(&cd)
+
+			var got []string
+			err := cd.Validate(testFactories, testTypes)
+			if err != nil {
+				errs, ok := err.(ValidationErrors)
+				if !ok {
+					t.Fatalf("Validate() error = %T, want ValidationErrors", err)
+				}
+				for _, fe := range errs {
+					got = append(got, fe.Path)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() problems at %v, want %v (%v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidationErrorMessages(t *testing.T) {
+	cd := validContainer()
+	cd.Lifecycle.RestartDelay = -1
+	cd.Lifecycle.StateTimeouts = map[string]int{"ACTIVE": 60000, "failed": 60000, "RELOAD": 1000, "standby": -1, "waiting": 1000}
+	cd.Components[1].Name = "gw"
+
+	err := cd.Validate(testFactories, testTypes)
+	if err == nil {
+		t.Fatal("Validate() succeeded")
+	}
+	// every problem is reported on its own line, prefixed with the path of the field
+	want := []string{
+		"7 configuration problem(s):",
+		"  lifecycle.restartDelay: must not be negative",
+		"  lifecycle.stateTimeouts.ACTIVE: is not allowed, ACTIVE is not left on a timeout",
+		"  lifecycle.stateTimeouts.RELOAD: is not allowed, the reload of a component is limited by its reload.verifyTimeout",
+		"  lifecycle.stateTimeouts.failed: is not allowed, the restart of a FAILED component is delayed by lifecycle.restartDelay",
+		"  lifecycle.stateTimeouts.standby: must not be negative",
+		"  lifecycle.stateTimeouts.waiting: unknown state, must be one of UNKNOWN, UNSATISFIED, RESOLVED, STANDBY",
+		"  components[1].name: [gw] is already used by components[0]",
+	}
+	if got := err.Error(); got != strings.Join(want, "\n") {
+		t.Errorf("Validate() error =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
@@ -12,6 +12,13 @@ import (
 
 var log = logger.GetLogger("jsonclient")
 
+// Response http response details
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
 // JSONClient json client utility for http client operations like GET, POST, PUT, etc.
 type JSONClient struct {
 	inbox   string
@@ -60,6 +67,45 @@ func (jsonClient *JSONClient) Get(path string) ([]byte, error) {
 	return resBody, nil
 }
 
+// GetResponse performs http GET with the given request headers and returns the full response
+func (jsonClient *JSONClient) GetResponse(path string, headers map[string]string) (*Response, error) {
+	requestURL, err := jsonClient.getRequestURL(path)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		log.Errorf("GET request to %s failed. Reason: %s", requestURL, err)
+		return nil, err
+	}
+	req.Header.Set("accept", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	httpClient := getHTTPClient()
+	log.Debugf("GET request to %s", requestURL)
+	res, err := httpClient.Do(req)
+	if err != nil {
+		log.Errorf("GET request to %s failed. Reason: %s", requestURL, err)
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Errorf("GET request to %s failed. Reason: %s", requestURL, err)
+		return nil, err
+	}
+
+	return &Response{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       resBody,
+	}, nil
+}
+
 // Post performs http POST
 func (jsonClient *JSONClient) Post(path string, payloadMap map[string]interface{}) ([]byte, error) {
 	requestURL, err := jsonClient.getRequestURL(path)
@@ -130,6 +176,38 @@ func (jsonClient *JSONClient) Put(path string, payloadMap map[string]interface{}
 	return resBody, nil
 }
 
+// Delete performs http DELETE
+func (jsonClient *JSONClient) Delete(path string) ([]byte, error) {
+	requestURL, err := jsonClient.getRequestURL(path)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("DELETE request to %s", requestURL)
+	req, err := http.NewRequest(http.MethodDelete, requestURL, nil)
+	if err != nil {
+		log.Errorf("DELETE request to %s failed. Reason: %s", requestURL, err)
+		return nil, err
+	}
+	req.Header.Set("accept", "application/json")
+
+	httpClient := getHTTPClient()
+	res, err := httpClient.Do(req)
+	if err != nil {
+		log.Errorf("DELETE request to %s failed. Reason: %s", requestURL, err)
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Errorf("DELETE request to %s failed. Reason: %s", requestURL, err)
+		return nil, err
+	}
+
+	return resBody, nil
+}
+
 func getHTTPClient() *http.Client {
 	httpClient := &http.Client{}
 	return httpClient
@@ -0,0 +1,83 @@
+package statefile
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/rameshpolishetti/mlca/internal/core/common/util"
+)
+
+// State agent state persisted across agent restarts
+type State struct {
+	// identity of the container the state belongs to
+	Name          string `json:"name"`
+	ComponentType string `json:"componentType"`
+	Cluster       string `json:"cluster"`
+	Zone          string `json:"zone"`
+
+	// registration
+	TmgcID    string `json:"tmgcId"`
+	ZoneID    string `json:"zoneId"`
+	ClusterID string `json:"clusterId"`
+
+	Components []Component `json:"components"`
+	SavedTime  time.Time   `json:"savedTime"`
+}
+
+// Component persisted state of a managed component
+type Component struct {
+	Name        string `json:"name"`
+	ComponentID string `json:"componentId"`
+	Pid         int    `json:"pid"`
+	State       string `json:"state"`
+}
+
+// Load reads the state file, a missing file returns nil
+func Load(filename string) (*State, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := &State{}
+	err = json.Unmarshal(data, s)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save writes the state file atomically
+func Save(filename string, s State) error {
+	s.SavedTime = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(filename, data, 0644)
+}
+
+// Matches returns whether the state belongs to the container, a state saved for another
+// identity must not be resumed
+func (s *State) Matches(cd config.ContainerDaemon) bool {
+	return s.Name == cd.Name &&
+		s.ComponentType == cd.ComponentType &&
+		s.Cluster == cd.Cluster &&
+		s.Zone == cd.Zone
+}
+
+// Component returns the persisted state of a managed component
+func (s *State) Component(name string) (Component, bool) {
+	for _, c := range s.Components {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Component{}, false
+}
@@ -0,0 +1,191 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultStopTimeout time a process is given to exit after SIGTERM before it is killed
+const DefaultStopTimeout = 10 * time.Second
+
+// adoptedPollInterval interval an adopted process is checked for exit
+const adoptedPollInterval = time.Second
+
+// Process child process started from a component script, or adopted from a previous agent run
+type Process struct {
+	script  string
+	process *os.Process
+	exited  chan struct{}
+}
+
+// StartProcess starts the script as a child process, stdout and stderr are appended to logFile when set
+func StartProcess(script, logFile string) (*Process, error) {
+	log.Infof("Starting the script [%s]", script)
+	scriptTokens := strings.Fields(script)
+	if len(scriptTokens) == 0 {
+		return nil, errors.New("empty script")
+	}
+
+	cmd := exec.Command(scriptTokens[0], scriptTokens[1:]...)
+	var out *os.File
+	if logFile != "" {
+		err := os.MkdirAll(filepath.Dir(logFile), 0755)
+		if err != nil {
+			return nil, err
+		}
+		out, err = os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Errorf("Not able to open log file %s - %s", logFile, err)
+			return nil, err
+		}
+		cmd.Stdout = out
+		cmd.Stderr = out
+	}
+
+	err := cmd.Start()
+	if err != nil {
+		log.Errorf("Not able to run the script [%s] with error - %s", script, err)
+		if out != nil {
+			out.Close()
+		}
+		return nil, err
+	}
+
+	p := &Process{
+		script:  script,
+		process: cmd.Process,
+		exited:  make(chan struct{}),
+	}
+	go func() {
+		err := cmd.Wait()
+		log.Infof("Script [%s] with pid %d exited: %v", script, cmd.Process.Pid, err)
+		if out != nil {
+			out.Close()
+		}
+		close(p.exited)
+	}()
+
+	return p, nil
+}
+
+// AdoptProcess attaches to a process started by a previous agent run, which must still be
+// running the script. As the process is not our child its exit is detected by polling.
+func AdoptProcess(pid int, script string) (*Process, error) {
+	if pid <= 0 || !processAlive(pid) {
+		return nil, fmt.Errorf("process %d is not running", pid)
+	}
+	if !runsScript(pid, script) {
+		return nil, fmt.Errorf("process %d is not running the script [%s]", pid, script)
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Adopted the script [%s] with pid %d", script, pid)
+
+	p := &Process{
+		script:  script,
+		process: process,
+		exited:  make(chan struct{}),
+	}
+	go func() {
+		ticker := time.NewTicker(adoptedPollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if !processAlive(pid) {
+				log.Infof("Script [%s] with pid %d exited", script, pid)
+				close(p.exited)
+				return
+			}
+		}
+	}()
+	return p, nil
+}
+
+// processAlive returns whether a process exists and is not a zombie, zombies
+// reparented to the agent are reaped
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	if err != nil && err != syscall.EPERM {
+		return false
+	}
+
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return true
+	}
+	// pid (comm) state ...
+	if i := bytes.LastIndexByte(stat, ')'); i >= 0 && i+2 < len(stat) && stat[i+2] == 'Z' {
+		var status syscall.WaitStatus
+		syscall.Wait4(pid, &status, syscall.WNOHANG, nil)
+		return false
+	}
+	return true
+}
+
+// runsScript returns whether the command of a process is the one of the script,
+// a reused pid must not be adopted. Without /proc the check is skipped.
+func runsScript(pid int, script string) bool {
+	scriptTokens := strings.Fields(script)
+	if len(scriptTokens) == 0 {
+		return false
+	}
+	cmdline, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return true
+	}
+	args := strings.Split(string(cmdline), "\x00")
+	return filepath.Base(args[0]) == filepath.Base(scriptTokens[0])
+}
+
+// Pid returns process id
+func (p *Process) Pid() int {
+	return p.process.Pid
+}
+
+// Running returns whether the process is still running
+func (p *Process) Running() bool {
+	select {
+	case <-p.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// Signal sends a signal to the process
+func (p *Process) Signal(sig os.Signal) error {
+	if !p.Running() {
+		return errors.New("process is not running")
+	}
+	return p.process.Signal(sig)
+}
+
+// Stop terminates the process, it is killed when it does not exit within the timeout
+func (p *Process) Stop(timeout time.Duration) error {
+	if !p.Running() {
+		return nil
+	}
+
+	log.Infof("Stopping the script [%s] with pid %d", p.script, p.Pid())
+	err := p.process.Signal(syscall.SIGTERM)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-p.exited:
+		return nil
+	case <-time.After(timeout):
+		log.Infof("Script [%s] did not exit within %s, killing it", p.script, timeout)
+		return p.process.Kill()
+	}
+}
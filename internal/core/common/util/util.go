@@ -2,8 +2,11 @@ package util
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/rameshpolishetti/mlca/logger"
@@ -39,3 +42,36 @@ func RunScript(script string) {
 		log.Fatal("Not able to run the script with error - ", err)
 	}
 }
+
+// WriteFileAtomic writes data to a temp file in the same directory and renames it over filename,
+// so readers never see a partially written file
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(filename)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := ioutil.TempFile(dir, "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(data)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmpFile.Name(), perm)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), filename)
+}
@@ -0,0 +1,41 @@
+package version
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// build metadata, set at build time with
+//
+//	go build -ldflags "-X github.com/rameshpolishetti/mlca/internal/core/common/version.Version=1.0.0 \
+//	  -X github.com/rameshpolishetti/mlca/internal/core/common/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/rameshpolishetti/mlca/internal/core/common/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version = "dev"
+	Commit  = "unknown"
+	Date    = "unknown"
+)
+
+// Info build metadata of the running agent
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+}
+
+// Get returns the build metadata
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+}
+
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s %s)", i.Version, i.Commit, i.Date, i.GoVersion, i.Platform)
+}
@@ -1,5 +1,7 @@
 package component
 
+import "github.com/rameshpolishetti/mlca/internal/core/common/config"
+
 // Component is container component managed by container agent
 type Component interface {
 	/*
@@ -8,7 +10,7 @@ type Component interface {
 	* RESOLVED	activate()	launchComponent()
 	* STANDBY	standby()	prepareForActive()
 	* ACTIVE	monitor()	watchComponent()
-	* RELOAD	reload()
+	* RELOAD	reload()	buildConfiguration() reload()
 	* RECYCLE	waitingForDependencies()
 	* DISABLED	deavtivate()
 	 */
@@ -17,5 +19,23 @@ type Component interface {
 	BuildConfiguration() bool
 	LaunchComponent() bool
 	PrepareForActive() bool
+	// WatchComponent returns whether the running component passes its health probe
 	WatchComponent() bool
+	// Running returns whether the component process is running
+	Running() bool
+
+	// NeedsReload returns whether the component configuration changed while ACTIVE
+	NeedsReload() bool
+	// Reload applies the rebuilt configuration to the running component
+	Reload() bool
+	// Stop stops the component process and releases its watchers
+	Stop() bool
+
+	// SetContainerInstance updates the container identity, once the registry assigned its ids
+	SetContainerInstance(ci config.ContainerInstance)
+
+	// Pid returns the pid of the component process, zero when it is not running
+	Pid() int
+	// Adopt attaches to the still running process of a previous agent run
+	Adopt(pid int) bool
 }
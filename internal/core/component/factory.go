@@ -0,0 +1,64 @@
+package component
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+)
+
+// Factory creates a managed component from its configuration
+type Factory func(mc config.ManagedComponent, s Services) Component
+
+var (
+	factoriesLock sync.RWMutex
+	factories     = make(map[string]Factory)
+	// factory names by the component type selecting them when the factory field is not set
+	types = make(map[string]string)
+)
+
+// RegisterFactory registers a component factory under the name used by the factory field of the configuration,
+// and under the component type that selected it before the factory field was introduced
+func RegisterFactory(name, componentType string, f Factory) {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+	if _, ok := factories[name]; ok {
+		log.Panicf("component factory %s is already registered", name)
+	}
+	if _, ok := types[componentType]; ok {
+		log.Panicf("component type %s is already registered", componentType)
+	}
+	factories[name] = f
+	types[componentType] = name
+}
+
+// GetFactory returns the component factory registered under name
+func GetFactory(name string) (Factory, bool) {
+	factoriesLock.RLock()
+	defer factoriesLock.RUnlock()
+	f, ok := factories[name]
+	return f, ok
+}
+
+// FactoryNames returns names of the registered component factories
+func FactoryNames() []string {
+	factoriesLock.RLock()
+	defer factoriesLock.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FactoryTypes returns the names of the registered component factories by the component type selecting them
+func FactoryTypes() map[string]string {
+	factoriesLock.RLock()
+	defer factoriesLock.RUnlock()
+	byType := make(map[string]string, len(types))
+	for t, name := range types {
+		byType[t] = name
+	}
+	return byType
+}
@@ -0,0 +1,222 @@
+package lfa
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/rameshpolishetti/mlca/internal/core/common/util"
+)
+
+// fluentBitTemplate fluent-bit configuration tailing the captured logs of the managed components
+const fluentBitTemplate = `[SERVICE]
+    Flush        5
+    Daemon       Off
+    Log_Level    info
+{{with .ForwardPort}}
+[INPUT]
+    Name    forward
+    Listen  127.0.0.1
+    Port    {{.}}
+{{end}}{{range .Inputs}}
+[INPUT]
+    Name              tail
+    Tag               {{.Tag}}
+    Path              {{.Path}}
+    DB                {{.DB}}
+    Refresh_Interval  5
+
+[FILTER]
+    Name    record_modifier
+    Match   {{.Tag}}
+    Record  component {{.Name}}
+    Record  qualifier {{.Qualifier}}
+{{end}}
+[FILTER]
+    Name    record_modifier
+    Match   {{.TagPrefix}}.*
+    Record  TMG_CLUSTER_NAME {{.Cluster}}
+    Record  TMG_ZONE_NAME {{.Zone}}
+    Record  POD_IP {{.PodIP}}
+{{- with .Container}}
+{{- if .ClusterID}}
+    Record  TMG_CLUSTER_ID {{.ClusterID}}
+{{- end}}
+{{- if .ZoneID}}
+    Record  TMG_ZONE_ID {{.ZoneID}}
+{{- end}}
+{{- if .TmgcID}}
+    Record  TMGC_ID {{.TmgcID}}
+{{- end}}
+{{- end}}
+{{range .Outputs}}
+[OUTPUT]
+    Name   {{.Name}}
+    Match  {{.Match}}
+{{- range $k, $v := .Properties}}
+    {{$k}}  {{$v}}
+{{- end}}
+{{end}}`
+
+const tagPrefix = "mlca"
+
+// fluentBitInput tail input of a managed component log file
+type fluentBitInput struct {
+	Name      string
+	Qualifier string
+	Tag       string
+	Path      string
+	DB        string
+}
+
+// fluentBitData data the fluent-bit configuration is rendered with
+type fluentBitData struct {
+	TagPrefix string
+	Cluster   string
+	Zone      string
+	PodIP     string
+	Container config.ContainerInstance
+	// ForwardPort port the agent logs are received on, zero when no forward sink targets this component
+	ForwardPort int
+	Inputs      []fluentBitInput
+	Outputs     []config.LogOutput
+}
+
+// renderFluentBitConfiguration renders the fluent-bit configuration and writes it atomically,
+// the rendered configuration is returned so that changes can be detected
+func (lfac *LFAComponent) renderFluentBitConfiguration() ([]byte, error) {
+	data, err := lfac.fluentBitData()
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(lfac.Qualifier).Parse(fluentBitTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if lfac.ConfigFile != "" {
+		lfac.log.Infof("writing fluent-bit configuration to %s", lfac.ConfigFile)
+		err = util.WriteFileAtomic(lfac.ConfigFile, buf.Bytes(), 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (lfac *LFAComponent) fluentBitData() (*fluentBitData, error) {
+	cDaemon := lfac.services.Container
+	lfac.renderedInputs = lfac.inputNames()
+	data := &fluentBitData{
+		TagPrefix: tagPrefix,
+		// prefer the metadata injected into the pod, as the agent logger does
+		Cluster:   envOrDefault("TMG_CLUSTER_NAME", lfac.ContainerInstance.Cluster),
+		Zone:      envOrDefault("TMG_ZONE_NAME", lfac.ContainerInstance.Zone),
+		PodIP:     envOrDefault("POD_IP", lfac.ContainerInstance.IP),
+		Container: lfac.ContainerInstance,
+	}
+	if port, ok := cDaemon.Logging.ForwardPort(lfac.ManagedComponent); ok {
+		data.ForwardPort = port
+	}
+
+	for _, mc := range cDaemon.Components {
+		if mc.Name == lfac.Name {
+			continue
+		}
+		tag := tagPrefix + "." + sanitizeTag(mc.Qualifier)
+		data.Inputs = append(data.Inputs, fluentBitInput{
+			Name:      mc.Name,
+			Qualifier: mc.Qualifier,
+			Tag:       tag,
+			Path:      cDaemon.ComponentLogFile(mc.Name),
+			DB:        filepath.Join(cDaemon.GetLogDir(), "."+sanitizeTag(mc.Qualifier)+".fluent-bit.db"),
+		})
+	}
+
+	data.Outputs = append(data.Outputs, lfac.Outputs...)
+	managerOutputs, err := lfac.managerOutputs()
+	if err != nil {
+		return nil, err
+	}
+	data.Outputs = append(data.Outputs, managerOutputs...)
+	if len(data.Outputs) == 0 {
+		data.Outputs = []config.LogOutput{{Name: "stdout"}}
+	}
+	for i := range data.Outputs {
+		if data.Outputs[i].Match == "" {
+			data.Outputs[i].Match = tagPrefix + ".*"
+		}
+	}
+
+	return data, nil
+}
+
+// inputNames returns names of the inputs: the agent forward input and the components to tail,
+// every other managed component but never our own output
+func (lfac *LFAComponent) inputNames() []string {
+	names := []string{}
+	if port, ok := lfac.services.Container.Logging.ForwardPort(lfac.ManagedComponent); ok {
+		names = append(names, fmt.Sprintf("forward:%d", port))
+	}
+	for _, mc := range lfac.services.Container.Components {
+		if mc.Name != lfac.Name {
+			names = append(names, mc.Name)
+		}
+	}
+	return names
+}
+
+// inputsChanged returns whether managed components or the forward input were added or removed since the configuration was rendered
+func (lfac *LFAComponent) inputsChanged() bool {
+	if lfac.renderedInputs == nil {
+		return false
+	}
+	return !reflect.DeepEqual(lfac.renderedInputs, lfac.inputNames())
+}
+
+// managerOutputs returns the outputs published by the cluster manager
+func (lfac *LFAComponent) managerOutputs() ([]config.LogOutput, error) {
+	cc := lfac.managedCfg.Current()
+	if cc == nil || len(cc.Configuration) == 0 {
+		return nil, nil
+	}
+
+	published := struct {
+		Outputs []config.LogOutput `json:"outputs"`
+	}{}
+	err := json.Unmarshal(cc.Configuration, &published)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manager configuration version %s: %s", cc.Version, err)
+	}
+	return published.Outputs, nil
+}
+
+func envOrDefault(key, defaultValue string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return defaultValue
+}
+
+// sanitizeTag replaces characters fluent-bit does not accept in tags
+func sanitizeTag(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '.' || r == '*' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, s)
+}
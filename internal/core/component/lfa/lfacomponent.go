@@ -1,52 +1,183 @@
 package lfa
 
 import (
+	"bytes"
+	"io/ioutil"
+
 	"github.com/rameshpolishetti/mlca/internal/core/common/config"
 	"github.com/rameshpolishetti/mlca/internal/core/common/util"
 	"github.com/rameshpolishetti/mlca/internal/core/component"
 	"github.com/rameshpolishetti/mlca/logger"
+	"github.com/sirupsen/logrus"
 )
 
 var log = logger.GetLogger("lfa")
 
+// FactoryName name of the log forwarding agent component factory
+const FactoryName = "FluentBitComponentFactory"
+
+// ComponentType component type selecting the log forwarding agent component factory
+const ComponentType = "Log"
+
+func init() {
+	component.RegisterFactory(FactoryName, ComponentType, NewLFAComponent)
+}
+
 // LFAComponent holds log forwarding agent component
 type LFAComponent struct {
 	// Name string
 	config.ManagedComponent
+	services   component.Services
+	log        *logrus.Logger
+	managedCfg *component.ManagedConfiguration
+	process    *util.Process
+
+	// rendered fluent-bit configuration, and the one the running process is using
+	rendered      []byte
+	runningConfig []byte
+	// names of the components tailed by the rendered configuration
+	renderedInputs []string
+	// the process was adopted, its configuration is compared with the rebuilt one once
+	adopted bool
 }
 
 // NewLFAComponent creates new LFAComponent
-func NewLFAComponent(mc config.ManagedComponent) component.Component {
+func NewLFAComponent(mc config.ManagedComponent, s component.Services) component.Component {
 	log.Infoln("init")
-	lfaComponent := &LFAComponent{}
+	lfaComponent := &LFAComponent{
+		services:   s,
+		log:        s.Logger(),
+		managedCfg: component.NewManagedConfiguration(mc.Qualifier, s),
+	}
 	lfaComponent.Clone(mc)
 
 	return lfaComponent
 }
 
 func (lfac *LFAComponent) Bootup() bool {
-	log.Infoln("Bootup")
+	lfac.log.Infoln("Bootup")
 	return true
 }
 
 func (lfac *LFAComponent) BuildConfiguration() bool {
-	log.Infoln("BuildConfiguration")
+	lfac.log.Infoln("BuildConfiguration")
+	if !lfac.managedCfg.Fetch() {
+		return false
+	}
+
+	rendered, err := lfac.renderFluentBitConfiguration()
+	if err != nil {
+		lfac.log.Errorf("unable to build fluent-bit configuration: %s", err)
+		return false
+	}
+	lfac.rendered = rendered
 	return true
 }
 
 func (lfac *LFAComponent) LaunchComponent() bool {
-	log.Infoln("LaunchComponent")
+	lfac.log.Infoln("LaunchComponent")
 	return true
 }
 
 func (lfac *LFAComponent) PrepareForActive() bool {
-	log.Infoln("PrepareForActive")
+	lfac.log.Infoln("PrepareForActive")
+	if lfac.Running() {
+		return true
+	}
 	// run script
-	util.RunScript(lfac.Script)
-	return true
+	return lfac.startProcess()
 }
 
 func (lfac *LFAComponent) WatchComponent() bool {
-	log.Infoln("WatchComponent")
+	lfac.log.Infoln("WatchComponent")
+	return component.IsHealthy(lfac.ManagedComponent)
+}
+
+func (lfac *LFAComponent) Running() bool {
+	return component.IsRunning(lfac.process)
+}
+
+func (lfac *LFAComponent) NeedsReload() bool {
+	return lfac.managedCfg.Changed() || lfac.inputsChanged() || lfac.adoptedConfigChanged()
+}
+
+func (lfac *LFAComponent) Reload() bool {
+	lfac.log.Infoln("Reload")
+	current := lfac.currentConfig()
+	if lfac.process != nil && lfac.process.Running() && bytes.Equal(current, lfac.runningConfig) {
+		lfac.log.Infoln("fluent-bit configuration unchanged, not restarting")
+		return true
+	}
+
+	if !component.ReloadProcess(lfac.ManagedComponent, lfac.process, lfac.startProcess) {
+		return false
+	}
+	lfac.runningConfig = current
+	return true
+}
+
+// currentConfig returns the configuration on disk, which differs from the rendered one after a rollback
+func (lfac *LFAComponent) currentConfig() []byte {
+	if lfac.ConfigFile == "" {
+		return lfac.rendered
+	}
+	data, err := ioutil.ReadFile(lfac.ConfigFile)
+	if err != nil {
+		return lfac.rendered
+	}
+	return data
+}
+
+func (lfac *LFAComponent) Stop() bool {
+	lfac.log.Infoln("Stop")
+	lfac.managedCfg.Stop()
+	if lfac.process != nil {
+		err := lfac.process.Stop(util.DefaultStopTimeout)
+		if err != nil {
+			lfac.log.Errorln(err)
+			return false
+		}
+	}
+	return true
+}
+
+// Pid returns the pid of the fluent-bit process, zero when it is not running
+func (lfac *LFAComponent) Pid() int {
+	if lfac.process == nil || !lfac.process.Running() {
+		return 0
+	}
+	return lfac.process.Pid()
+}
+
+// Adopt attaches to the fluent-bit process of a previous agent run
+func (lfac *LFAComponent) Adopt(pid int) bool {
+	p, err := util.AdoptProcess(pid, lfac.Script)
+	if err != nil {
+		lfac.log.Infof("not adopting process %d: %s", pid, err)
+		return false
+	}
+	lfac.process = p
+	lfac.runningConfig = lfac.currentConfig()
+	lfac.adopted = true
+	return true
+}
+
+// adoptedConfigChanged returns, once, whether the configuration rebuilt after adopting the
+// process differs from the one the process was started with
+func (lfac *LFAComponent) adoptedConfigChanged() bool {
+	if !lfac.adopted {
+		return false
+	}
+	lfac.adopted = false
+	return !bytes.Equal(lfac.runningConfig, lfac.currentConfig())
+}
+
+func (lfac *LFAComponent) startProcess() bool {
+	p, err := util.StartProcess(lfac.Script, lfac.services.Container.ComponentLogFile(lfac.Name))
+	if err != nil {
+		return false
+	}
+	lfac.process = p
+	lfac.runningConfig = lfac.currentConfig()
 	return true
 }
@@ -0,0 +1,80 @@
+package component
+
+import (
+	"sync"
+
+	"github.com/rameshpolishetti/mlca/internal/core/service"
+)
+
+// ManagedConfiguration configuration a component pulls from the cluster manager
+type ManagedConfiguration struct {
+	qualifier string
+	services  Services
+
+	mutex   sync.Mutex
+	current *service.ComponentConfiguration
+	changed bool
+	watcher *service.Watcher
+}
+
+// NewManagedConfiguration creates new ManagedConfiguration for the component qualifier
+func NewManagedConfiguration(qualifier string, s Services) *ManagedConfiguration {
+	return &ManagedConfiguration{
+		qualifier: qualifier,
+		services:  s,
+	}
+}
+
+// Fetch fetches the latest configuration and starts watching the manager for newer versions
+func (mcfg *ManagedConfiguration) Fetch() bool {
+	if mcfg.services.DryRun || mcfg.services.Manager == nil || !mcfg.services.Manager.Enabled() {
+		return true
+	}
+
+	cc, err := mcfg.services.Manager.FetchConfiguration(mcfg.qualifier)
+	if err != nil {
+		return false
+	}
+
+	mcfg.mutex.Lock()
+	defer mcfg.mutex.Unlock()
+	mcfg.current = cc
+	mcfg.changed = false
+
+	if mcfg.watcher == nil {
+		version := ""
+		if cc != nil {
+			version = cc.Version
+		}
+		mcfg.watcher = mcfg.services.Manager.Watch(mcfg.qualifier, version, mcfg.services.Container.Lifecycle.GetConfigPollInterval(), func(*service.ComponentConfiguration) {
+			mcfg.mutex.Lock()
+			defer mcfg.mutex.Unlock()
+			mcfg.changed = true
+		})
+	}
+	return true
+}
+
+// Current returns the last fetched configuration, nil if nothing is published
+func (mcfg *ManagedConfiguration) Current() *service.ComponentConfiguration {
+	mcfg.mutex.Lock()
+	defer mcfg.mutex.Unlock()
+	return mcfg.current
+}
+
+// Changed returns whether the manager published a newer configuration since the last fetch
+func (mcfg *ManagedConfiguration) Changed() bool {
+	mcfg.mutex.Lock()
+	defer mcfg.mutex.Unlock()
+	return mcfg.changed
+}
+
+// Stop stops watching the manager
+func (mcfg *ManagedConfiguration) Stop() {
+	mcfg.mutex.Lock()
+	defer mcfg.mutex.Unlock()
+	if mcfg.watcher != nil {
+		mcfg.watcher.Stop()
+		mcfg.watcher = nil
+	}
+}
@@ -0,0 +1,248 @@
+package mgw
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"text/template"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/rameshpolishetti/mlca/internal/core/common/util"
+	"github.com/rameshpolishetti/mlca/internal/core/service"
+)
+
+const (
+	defaultGatewayPort = 9096
+	restTriggerType    = "github.com/TIBCOSoftware/flogo-contrib/trigger/rest"
+	httpServiceType    = "http"
+)
+
+// defaultGatewayTemplate mashling gateway configuration used when no configTemplate is set
+const defaultGatewayTemplate = `{
+  "mashling_schema": "1.0",
+  "gateway": {
+    "name": {{json .Name}},
+    "version": "1.0.0",
+    "description": "Generated by container agent for {{.Qualifier}}",
+    "triggers": {{json .Triggers}},
+    "dispatches": {{json .Dispatches}},
+    "services": {{json .Services}}
+  }
+}
+`
+
+// gatewayData data the gateway configuration template is rendered with
+type gatewayData struct {
+	Name      string
+	Qualifier string
+	Port      int
+	Upstreams map[string][]service.Instance
+	// Container identity of the container the gateway runs in
+	Container config.ContainerInstance
+
+	Triggers   []interface{}
+	Dispatches []interface{}
+	Services   []interface{}
+
+	// Configuration published by the cluster manager, empty when nothing is published
+	Configuration map[string]interface{}
+}
+
+// gatewayConfig subset of the mashling gateway schema checked before the configuration is written
+type gatewayConfig struct {
+	MashlingSchema string `json:"mashling_schema"`
+	Gateway        struct {
+		Name     string `json:"name"`
+		Triggers []struct {
+			Name     string `json:"name"`
+			Handlers []struct {
+				Dispatch string `json:"dispatch"`
+			} `json:"handlers"`
+		} `json:"triggers"`
+		Dispatches []struct {
+			Name   string `json:"name"`
+			Routes []struct {
+				Steps []struct {
+					Service string `json:"service"`
+				} `json:"steps"`
+			} `json:"routes"`
+		} `json:"dispatches"`
+		Services []struct {
+			Name string `json:"name"`
+		} `json:"services"`
+	} `json:"gateway"`
+}
+
+// renderGatewayConfiguration renders, validates and atomically writes the mashling gateway configuration
+func (mgwc *MicrogatewayComponent) renderGatewayConfiguration() error {
+	if mgwc.ConfigFile == "" {
+		mgwc.log.Infoln("no configFile set, skipping gateway configuration")
+		return nil
+	}
+
+	tmplText := defaultGatewayTemplate
+	if mgwc.ConfigTemplate != "" {
+		data, err := ioutil.ReadFile(mgwc.ConfigTemplate)
+		if err != nil {
+			return err
+		}
+		tmplText = string(data)
+	}
+
+	tmpl, err := template.New(mgwc.Qualifier).Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(tmplText)
+	if err != nil {
+		return err
+	}
+
+	data, err := mgwc.gatewayData()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		return err
+	}
+
+	err = validateGatewayConfiguration(buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// indent so the written file is readable regardless of the template layout
+	var out bytes.Buffer
+	err = json.Indent(&out, buf.Bytes(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	mgwc.log.Infof("writing gateway configuration to %s", mgwc.ConfigFile)
+	return util.WriteFileAtomic(mgwc.ConfigFile, out.Bytes(), 0644)
+}
+
+// gatewayData collects triggers, dispatches and services from the manager configuration and discovered upstreams
+func (mgwc *MicrogatewayComponent) gatewayData() (*gatewayData, error) {
+	port := mgwc.Port
+	if port == 0 {
+		port = defaultGatewayPort
+	}
+
+	data := &gatewayData{
+		Name:          mgwc.Name,
+		Qualifier:     mgwc.Qualifier,
+		Port:          port,
+		Upstreams:     make(map[string][]service.Instance),
+		Container:     mgwc.ContainerInstance,
+		Configuration: map[string]interface{}{},
+	}
+
+	if cc := mgwc.managedCfg.Current(); cc != nil && len(cc.Configuration) > 0 {
+		err := json.Unmarshal(cc.Configuration, &data.Configuration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manager configuration version %s: %s", cc.Version, err)
+		}
+	}
+	data.Triggers = listValue(data.Configuration, "triggers")
+	data.Dispatches = listValue(data.Configuration, "dispatches")
+	data.Services = listValue(data.Configuration, "services")
+
+	// every discovered upstream instance becomes an http backend service, named by its registration
+	// as replicas of a component register with the same name
+	for _, componentType := range mgwc.ManagedComponent.Upstreams {
+		instances := mgwc.Upstreams(componentType)
+		data.Upstreams[componentType] = instances
+		for _, instance := range instances {
+			data.Services = append(data.Services, map[string]interface{}{
+				"name":        componentType + "-" + instance.TmgcId,
+				"description": "discovered " + componentType + " instance " + instance.Name,
+				"type":        httpServiceType,
+				"settings": map[string]interface{}{
+					"url": "http://" + net.JoinHostPort(instance.Host, instance.Port.String()),
+				},
+			})
+		}
+	}
+
+	// without published triggers expose a rest trigger on the gateway port
+	if len(data.Triggers) == 0 {
+		data.Triggers = []interface{}{
+			map[string]interface{}{
+				"name":        "rest_trigger",
+				"description": "gateway rest trigger",
+				"type":        restTriggerType,
+				"settings": map[string]interface{}{
+					"port": strconv.Itoa(port),
+				},
+				"handlers": []interface{}{},
+			},
+		}
+	}
+
+	return data, nil
+}
+
+func listValue(m map[string]interface{}, key string) []interface{} {
+	if l, ok := m[key].([]interface{}); ok {
+		return l
+	}
+	return []interface{}{}
+}
+
+// validateGatewayConfiguration checks the rendered configuration is valid json and its references resolve
+func validateGatewayConfiguration(data []byte) error {
+	gc := &gatewayConfig{}
+	err := json.Unmarshal(data, gc)
+	if err != nil {
+		return fmt.Errorf("rendered gateway configuration is not valid json: %s", err)
+	}
+
+	if gc.MashlingSchema == "" {
+		return errors.New("rendered gateway configuration has no mashling_schema")
+	}
+	if gc.Gateway.Name == "" {
+		return errors.New("rendered gateway configuration has no gateway name")
+	}
+	if len(gc.Gateway.Triggers) == 0 {
+		return errors.New("rendered gateway configuration has no triggers")
+	}
+
+	services := make(map[string]bool)
+	for _, s := range gc.Gateway.Services {
+		if services[s.Name] {
+			return fmt.Errorf("rendered gateway configuration has duplicate service %s", s.Name)
+		}
+		services[s.Name] = true
+	}
+
+	dispatches := make(map[string]bool)
+	for _, d := range gc.Gateway.Dispatches {
+		dispatches[d.Name] = true
+		for _, r := range d.Routes {
+			for _, step := range r.Steps {
+				if step.Service != "" && !services[step.Service] {
+					return fmt.Errorf("dispatch %s references unknown service %s", d.Name, step.Service)
+				}
+			}
+		}
+	}
+
+	for _, t := range gc.Gateway.Triggers {
+		for _, h := range t.Handlers {
+			if h.Dispatch != "" && !dispatches[h.Dispatch] {
+				return fmt.Errorf("trigger %s references unknown dispatch %s", t.Name, h.Dispatch)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package mgw
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/rameshpolishetti/mlca/internal/core/component"
+	"github.com/rameshpolishetti/mlca/internal/core/service"
+)
+
+// routedTemplate routes the rest trigger to the service of the cm-1 registration
+const routedTemplate = `{
+  "mashling_schema": "1.0",
+  "gateway": {
+    "name": {{json .Name}},
+    "triggers": [{"name": "rest", "handlers": [{"dispatch": "route"}]}],
+    "dispatches": [{"name": "route", "routes": [{"steps": [{"service": "trafficmanagers-cm-1"}]}]}],
+    "services": {{json .Services}}
+  }
+}`
+
+func TestRenderGatewayConfiguration(t *testing.T) {
+	replica := func(tmgcID, host string) service.Instance {
+		return service.Instance{TmgcId: tmgcID, Name: "tm-node", Host: host, Port: "9080"}
+	}
+
+	tests := []struct {
+		name         string
+		template     string
+		instances    []service.Instance
+		wantServices map[string]string
+		wantErr      string
+	}{
+		{
+			name:         "no upstreams",
+			wantServices: map[string]string{},
+		},
+		{
+			name:         "one upstream",
+			instances:    []service.Instance{replica("cm-1", "10.0.0.1")},
+			wantServices: map[string]string{"trafficmanagers-cm-1": "http://10.0.0.1:9080"},
+		},
+		{
+			name:      "replicas sharing a name",
+			instances: []service.Instance{replica("cm-1", "10.0.0.1"), replica("cm-2", "10.0.0.2")},
+			wantServices: map[string]string{
+				"trafficmanagers-cm-1": "http://10.0.0.1:9080",
+				"trafficmanagers-cm-2": "http://10.0.0.2:9080",
+			},
+		},
+		{
+			name:         "routed to a discovered upstream",
+			template:     routedTemplate,
+			instances:    []service.Instance{replica("cm-1", "10.0.0.1")},
+			wantServices: map[string]string{"trafficmanagers-cm-1": "http://10.0.0.1:9080"},
+		},
+		{
+			name:      "routed to an upstream that is gone",
+			template:  routedTemplate,
+			instances: []service.Instance{replica("cm-2", "10.0.0.2")},
+			wantErr:   "dispatch route references unknown service trafficmanagers-cm-1",
+		},
+		{
+			name:     "template without triggers",
+			template: `{"mashling_schema": "1.0", "gateway": {"name": {{json .Name}}}}`,
+			wantErr:  "no triggers",
+		},
+		{
+			name:     "template rendering invalid json",
+			template: `{"mashling_schema": "1.0", "gateway": {{.Name}}}`,
+			wantErr:  "not valid json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "mgw")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			mgwc := &MicrogatewayComponent{
+				log:        log,
+				managedCfg: component.NewManagedConfiguration("microgateway", component.Services{}),
+				upstreams:  map[string][]service.Instance{"trafficmanagers": tt.instances},
+			}
+			mgwc.ManagedComponent = config.ManagedComponent{
+				Name:       "TMG-Microgateway",
+				Qualifier:  "microgateway",
+				ConfigFile: filepath.Join(dir, "mashling.json"),
+				Upstreams:  []string{"trafficmanagers"},
+			}
+			if tt.template != "" {
+				mgwc.ConfigTemplate = filepath.Join(dir, "mashling.tmpl")
+				if err := ioutil.WriteFile(mgwc.ConfigTemplate, []byte(tt.template), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = mgwc.renderGatewayConfiguration()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("renderGatewayConfiguration() error = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := os.Stat(mgwc.ConfigFile); !os.IsNotExist(err) {
+					t.Errorf("an invalid configuration was written")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderGatewayConfiguration() error = %v", err)
+			}
+
+			written := struct {
+				Gateway struct {
+					Services []struct {
+						Name     string `json:"name"`
+						Settings struct {
+							URL string `json:"url"`
+						} `json:"settings"`
+					} `json:"services"`
+				} `json:"gateway"`
+			}{}
+			b, err := ioutil.ReadFile(mgwc.ConfigFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(b, &written); err != nil {
+				t.Fatal(err)
+			}
+			services := make(map[string]string)
+			for _, s := range written.Gateway.Services {
+				services[s.Name] = s.Settings.URL
+			}
+			if len(services) != len(tt.wantServices) {
+				t.Errorf("services = %v, want %v", services, tt.wantServices)
+			}
+			for name, url := range tt.wantServices {
+				if services[name] != url {
+					t.Errorf("service %s url = %q, want %q", name, services[name], url)
+				}
+			}
+		})
+	}
+}
@@ -1,25 +1,61 @@
 package mgw
 
 import (
+	"bytes"
+	"io/ioutil"
+	"sync"
+
 	"github.com/rameshpolishetti/mlca/internal/core/common/config"
 	"github.com/rameshpolishetti/mlca/internal/core/common/util"
 	"github.com/rameshpolishetti/mlca/internal/core/component"
+	"github.com/rameshpolishetti/mlca/internal/core/service"
 	"github.com/rameshpolishetti/mlca/logger"
+	"github.com/sirupsen/logrus"
 )
 
 var log = logger.GetLogger("tm")
 
+// FactoryName name of the microgateway component factory
+const FactoryName = "MashlingComponentFactory"
+
+// ComponentType component type selecting the microgateway component factory
+const ComponentType = "Microgateway"
+
+func init() {
+	component.RegisterFactory(FactoryName, ComponentType, NewMicrogatewayComponent)
+}
+
 // MicrogatewayComponent holds Microgateway component
 type MicrogatewayComponent struct {
 	// Name string
 	config.ManagedComponent
+	services   component.Services
+	log        *logrus.Logger
+	managedCfg *component.ManagedConfiguration
+	process    *util.Process
+	// configuration file an adopted process was started with, until it is compared with the rebuilt one
+	adoptedConfig []byte
+
+	// upstream instances discovered through registry, keyed by componentType. Every change
+	// reported by a watcher is a new generation, the configuration is rebuilt until the
+	// generation it was last built from is the latest one.
+	upstreamsLock       sync.RWMutex
+	upstreams           map[string][]service.Instance
+	upstreamsGeneration uint64
+	builtGeneration     uint64
+	watchers            map[string]*service.Watcher
 }
 
 // NewMicrogatewayComponent creates new MicrogatewayComponent component
-func NewMicrogatewayComponent(mc config.ManagedComponent) component.Component {
+func NewMicrogatewayComponent(mc config.ManagedComponent, s component.Services) component.Component {
 	log.Infoln("init")
 	mgwComponent := &MicrogatewayComponent{
-	// Name: name,
+		// Name: name,
+		services:   s,
+		log:        s.Logger(),
+		managedCfg: component.NewManagedConfiguration(mc.Qualifier, s),
+		upstreams:  make(map[string][]service.Instance),
+		watchers:   make(map[string]*service.Watcher),
 	}
 	mgwComponent.Clone(mc)
 
@@ -27,28 +63,174 @@ func NewMicrogatewayComponent(mc config.ManagedComponent) component.Component {
 }
 
 func (mgwc *MicrogatewayComponent) Bootup() bool {
-	log.Infoln("Bootup")
+	mgwc.log.Infoln("Bootup")
 	return true
 }
 
 func (mgwc *MicrogatewayComponent) BuildConfiguration() bool {
-	log.Infoln("BuildConfiguration")
+	mgwc.log.Infoln("BuildConfiguration")
+	// the rebuild takes the upstream changes reported so far, also when it fails the running
+	// configuration is kept until the upstreams change again instead of rebuilding on every heartbeat
+	mgwc.upstreamsLock.RLock()
+	generation := mgwc.upstreamsGeneration
+	mgwc.upstreamsLock.RUnlock()
+	defer mgwc.setBuiltGeneration(generation)
+
+	if !mgwc.discoverUpstreams() {
+		return false
+	}
+	if !mgwc.managedCfg.Fetch() {
+		return false
+	}
+
+	// stay UNSATISFIED until a valid gateway configuration is written
+	err := mgwc.renderGatewayConfiguration()
+	if err != nil {
+		mgwc.log.Errorf("unable to build gateway configuration: %s", err)
+		return false
+	}
 	return true
 }
 
 func (mgwc *MicrogatewayComponent) LaunchComponent() bool {
-	log.Infoln("LaunchComponent")
+	mgwc.log.Infoln("LaunchComponent")
 	return true
 }
 
 func (mgwc *MicrogatewayComponent) PrepareForActive() bool {
-	log.Infoln("PrepareForActive")
+	mgwc.log.Infoln("PrepareForActive")
+	if mgwc.Running() {
+		return true
+	}
 	// run script
-	util.RunScript(mgwc.Script)
-	return true
+	return mgwc.startProcess()
 }
 
 func (mgwc *MicrogatewayComponent) WatchComponent() bool {
-	log.Infoln("WatchComponent")
+	mgwc.log.Infoln("WatchComponent")
+	return component.IsHealthy(mgwc.ManagedComponent)
+}
+
+func (mgwc *MicrogatewayComponent) Running() bool {
+	return component.IsRunning(mgwc.process)
+}
+
+func (mgwc *MicrogatewayComponent) NeedsReload() bool {
+	mgwc.upstreamsLock.RLock()
+	defer mgwc.upstreamsLock.RUnlock()
+	return mgwc.builtGeneration != mgwc.upstreamsGeneration || mgwc.managedCfg.Changed() || mgwc.adoptedConfigChanged()
+}
+
+func (mgwc *MicrogatewayComponent) Reload() bool {
+	mgwc.log.Infoln("Reload")
+	return component.ReloadProcess(mgwc.ManagedComponent, mgwc.process, mgwc.startProcess)
+}
+
+func (mgwc *MicrogatewayComponent) Stop() bool {
+	mgwc.log.Infoln("Stop")
+	mgwc.managedCfg.Stop()
+	for componentType, w := range mgwc.watchers {
+		w.Stop()
+		delete(mgwc.watchers, componentType)
+	}
+	if mgwc.process != nil {
+		err := mgwc.process.Stop(util.DefaultStopTimeout)
+		if err != nil {
+			mgwc.log.Errorln(err)
+			return false
+		}
+	}
 	return true
 }
+
+// Pid returns the pid of the gateway process, zero when it is not running
+func (mgwc *MicrogatewayComponent) Pid() int {
+	if mgwc.process == nil || !mgwc.process.Running() {
+		return 0
+	}
+	return mgwc.process.Pid()
+}
+
+// Adopt attaches to the gateway process of a previous agent run
+func (mgwc *MicrogatewayComponent) Adopt(pid int) bool {
+	p, err := util.AdoptProcess(pid, mgwc.Script)
+	if err != nil {
+		mgwc.log.Infof("not adopting process %d: %s", pid, err)
+		return false
+	}
+	mgwc.process = p
+	if mgwc.ConfigFile != "" {
+		mgwc.adoptedConfig, _ = ioutil.ReadFile(mgwc.ConfigFile)
+	}
+	return true
+}
+
+// adoptedConfigChanged returns, once, whether the configuration rebuilt after adopting the
+// process differs from the one the process was started with
+func (mgwc *MicrogatewayComponent) adoptedConfigChanged() bool {
+	if mgwc.adoptedConfig == nil {
+		return false
+	}
+	current, _ := ioutil.ReadFile(mgwc.ConfigFile)
+	changed := !bytes.Equal(mgwc.adoptedConfig, current)
+	mgwc.adoptedConfig = nil
+	return changed
+}
+
+func (mgwc *MicrogatewayComponent) startProcess() bool {
+	p, err := util.StartProcess(mgwc.Script, mgwc.services.Container.ComponentLogFile(mgwc.Name))
+	if err != nil {
+		return false
+	}
+	mgwc.process = p
+	return true
+}
+
+// Upstreams returns the discovered instances of an upstream componentType
+func (mgwc *MicrogatewayComponent) Upstreams(componentType string) []service.Instance {
+	mgwc.upstreamsLock.RLock()
+	defer mgwc.upstreamsLock.RUnlock()
+	return mgwc.upstreams[componentType]
+}
+
+// discoverUpstreams looks up every upstream componentType in the registry and watches it for changes
+func (mgwc *MicrogatewayComponent) discoverUpstreams() bool {
+	if mgwc.services.DryRun {
+		mgwc.log.Infof("dry run, not discovering upstreams %v", mgwc.ManagedComponent.Upstreams)
+		return true
+	}
+	for _, componentType := range mgwc.ManagedComponent.Upstreams {
+		instances, err := mgwc.services.Registry.ListZoneInstances(componentType)
+		if err != nil || len(instances) == 0 {
+			mgwc.log.Infof("no instances of upstream %s found", componentType)
+			return false
+		}
+		mgwc.log.Infof("discovered %d instances of upstream %s", len(instances), componentType)
+		mgwc.setUpstreams(componentType, instances, false)
+
+		if _, ok := mgwc.watchers[componentType]; !ok {
+			ct := componentType
+			mgwc.watchers[ct] = mgwc.services.Registry.Watch(ct, instances, mgwc.services.Container.Lifecycle.GetDiscoveryInterval(), func(instances []service.Instance) {
+				mgwc.setUpstreams(ct, instances, true)
+			})
+		}
+	}
+	return true
+}
+
+// setUpstreams keeps the instances of an upstream componentType, a change reported by a watcher starts a new generation
+func (mgwc *MicrogatewayComponent) setUpstreams(componentType string, instances []service.Instance, changed bool) {
+	mgwc.upstreamsLock.Lock()
+	defer mgwc.upstreamsLock.Unlock()
+	mgwc.upstreams[componentType] = instances
+	if changed {
+		mgwc.upstreamsGeneration++
+	}
+}
+
+// setBuiltGeneration records the upstreams generation a configuration build started from
+func (mgwc *MicrogatewayComponent) setBuiltGeneration(generation uint64) {
+	mgwc.upstreamsLock.Lock()
+	defer mgwc.upstreamsLock.Unlock()
+	mgwc.builtGeneration = generation
+}
@@ -0,0 +1,128 @@
+package component
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/rameshpolishetti/mlca/internal/core/common/util"
+	"github.com/rameshpolishetti/mlca/logger"
+)
+
+var log = logger.GetLogger("component")
+
+const (
+	// ReloadSignal reload by sending a signal to the component process
+	ReloadSignal = "signal"
+	// ReloadHTTP reload by calling an admin endpoint of the component
+	ReloadHTTP = "http"
+	// ReloadRestart reload by restarting the component process
+	ReloadRestart = "restart"
+)
+
+var signals = map[string]os.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+	"SIGINT":  syscall.SIGINT,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+// ReloadProcess applies a changed configuration to the running process of a component with its
+// configured reload method, restart is called to (re)start the process for the restart method
+func ReloadProcess(mc config.ManagedComponent, p *util.Process, restart func() bool) bool {
+	switch mc.Reload.Method {
+	case ReloadSignal:
+		sigName := strings.ToUpper(mc.Reload.Signal)
+		if sigName == "" {
+			sigName = "SIGHUP"
+		} else if !strings.HasPrefix(sigName, "SIG") {
+			sigName = "SIG" + sigName
+		}
+		sig, ok := signals[sigName]
+		if !ok {
+			log.Errorf("unsupported reload signal %s for %s", mc.Reload.Signal, mc.Name)
+			return false
+		}
+		if p == nil {
+			return restart()
+		}
+		log.Infof("sending %s to %s", sigName, mc.Name)
+		err := p.Signal(sig)
+		if err != nil {
+			log.Errorf("unable to signal %s: %s", mc.Name, err)
+			return false
+		}
+		return true
+
+	case ReloadHTTP:
+		err := callAdminEndpoint(mc.Reload)
+		if err != nil {
+			log.Errorf("unable to reload %s: %s", mc.Name, err)
+			return false
+		}
+		return true
+
+	default:
+		if p != nil {
+			err := p.Stop(util.DefaultStopTimeout)
+			if err != nil {
+				log.Errorln(err)
+				return false
+			}
+		}
+		return restart()
+	}
+}
+
+// IsRunning returns whether the component process was started and did not exit
+func IsRunning(p *util.Process) bool {
+	return p != nil && p.Running()
+}
+
+// IsHealthy returns whether the component passes its health probe, components without a
+// health url are healthy as long as their process runs
+func IsHealthy(mc config.ManagedComponent) bool {
+	if mc.HealthURL == "" {
+		return true
+	}
+
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	res, err := httpClient.Get(mc.HealthURL)
+	if err != nil {
+		log.Debugf("health probe of %s failed: %s", mc.Name, err)
+		return false
+	}
+	res.Body.Close()
+	return res.StatusCode >= 200 && res.StatusCode < 300
+}
+
+func callAdminEndpoint(rs config.ReloadSettings) error {
+	if rs.URL == "" {
+		return fmt.Errorf("reload url is not configured")
+	}
+	method := rs.HTTPMethod
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	req, err := http.NewRequest(strings.ToUpper(method), rs.URL, bytes.NewReader(nil))
+	if err != nil {
+		return err
+	}
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("%s %s responded with %d", method, rs.URL, res.StatusCode)
+	}
+	return nil
+}
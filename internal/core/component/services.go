@@ -0,0 +1,27 @@
+package component
+
+import (
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/rameshpolishetti/mlca/internal/core/service"
+	"github.com/sirupsen/logrus"
+)
+
+// Services agent services available to managed components
+type Services struct {
+	Registry *service.RegistryProxy
+	Manager  *service.ManagerProxy
+	// Container is shared by all components and updated when the agent configuration is reloaded
+	Container *config.ContainerDaemon
+	// Log contextual logger of the component, tagging lines with its name, qualifier, tmgcId and state
+	Log *logrus.Logger
+	// DryRun components only render their configuration, without contacting the registry or the cluster manager
+	DryRun bool
+}
+
+// Logger returns the contextual logger of the component, or the component package logger when there is none
+func (s Services) Logger() *logrus.Logger {
+	if s.Log != nil {
+		return s.Log
+	}
+	return log
+}
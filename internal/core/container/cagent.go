@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -14,21 +16,32 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/rameshpolishetti/mlca/internal/core/common/statefile"
+	"github.com/rameshpolishetti/mlca/internal/core/common/version"
 	"github.com/rameshpolishetti/mlca/internal/core/service"
 	"github.com/rameshpolishetti/mlca/internal/core/service/lifecycleservice"
 )
 
 var log = logger.GetLogger("cagent")
 
-const (
-	HeartBeatInterval = 2000 * time.Millisecond
-)
-
 // ContainerAgent container agent
 type ContainerAgent struct {
+	// replaced by the lifecycle loop on configuration reload, the api handlers read it through daemonConfig
+	configLock        sync.RWMutex
 	containerDaemon   config.ContainerDaemon
 	RegService        *service.RegistryProxy
+	ManagerService    *service.ManagerProxy
 	LifecycleServices lifecycleservice.LifeCycleServices
+
+	// configuration reload
+	configLoader func() (config.ContainerDaemon, error)
+	reloadChan   chan struct{}
+
+	// component actions requested through the api
+	controlChan chan controlRequest
+
+	// state last persisted to the state file
+	savedState statefile.State
 }
 
 // NewContainerAgent creates new container agent
@@ -36,22 +49,76 @@ func NewContainerAgent(cDaemon config.ContainerDaemon) *ContainerAgent {
 
 	a := &ContainerAgent{
 		containerDaemon: cDaemon,
+		reloadChan:      make(chan struct{}, 1),
+		controlChan:     make(chan controlRequest),
 	}
 
-	// Init registry proxy service
+	// Init registry proxy service, shared by the container and all managed components
 	a.RegService = service.NewRegistryProxyService(cDaemon)
 
+	// Init cluster manager proxy service
+	a.ManagerService = service.NewManagerProxyService(cDaemon)
+
 	// load managed components
 
 	// init lifecycle services
-	a.LifecycleServices = lifecycleservice.NewLifeCycleServices(cDaemon)
+	a.LifecycleServices = lifecycleservice.NewLifeCycleServices(cDaemon, a.RegService, a.ManagerService)
 
 	return a
 }
 
 // Initialize initializes container agent
 func (ca *ContainerAgent) Initialize() {
-	// it it lifecycle service
+	// resume the registration and components of a previous agent run
+	ca.restoreState()
+}
+
+// SetConfigLoader sets the function used to reload the container configuration
+func (ca *ContainerAgent) SetConfigLoader(loader func() (config.ContainerDaemon, error)) {
+	ca.configLoader = loader
+}
+
+// RequestConfigReload asks the agent to reload its configuration, pending requests are coalesced
+func (ca *ContainerAgent) RequestConfigReload() {
+	select {
+	case ca.reloadChan <- struct{}{}:
+	default:
+	}
+}
+
+// reloadConfig loads the configuration again and applies the differences to the managed components
+func (ca *ContainerAgent) reloadConfig() {
+	if ca.configLoader == nil {
+		log.Infoln("configuration reload is not enabled")
+		return
+	}
+
+	cDaemon, err := ca.configLoader()
+	if err != nil {
+		log.Errorf("unable to reload container configuration: %s", err)
+		return
+	}
+
+	err = ca.LifecycleServices.ApplyConfiguration(cDaemon)
+	if err != nil {
+		log.Errorf("rejected container configuration change: %s", err)
+		return
+	}
+	ca.configLock.Lock()
+	ca.containerDaemon = cDaemon
+	ca.configLock.Unlock()
+	err = ConfigureLogging(cDaemon)
+	if err != nil {
+		log.Errorf("unable to configure logging: %s", err)
+	}
+	log.Infoln("container configuration reloaded")
+}
+
+// daemonConfig returns the container configuration in effect
+func (ca *ContainerAgent) daemonConfig() config.ContainerDaemon {
+	ca.configLock.RLock()
+	defer ca.configLock.RUnlock()
+	return ca.containerDaemon
 }
 
 // Start starts container agent
@@ -59,8 +126,20 @@ func (ca *ContainerAgent) Start() {
 
 	// start http server
 	router := mux.NewRouter()
+	router.HandleFunc("/version", ca.getVersion).Methods("GET")
 	pathStatus := fmt.Sprintf("/%s/status", ca.containerDaemon.Name)
 	router.HandleFunc(pathStatus, ca.getStatus).Methods("GET")
+	pathReady := fmt.Sprintf("/%s/ready", ca.containerDaemon.Name)
+	router.HandleFunc(pathReady, ca.getReady).Methods("GET")
+	pathLoggers := fmt.Sprintf("/%s/loggers", ca.containerDaemon.Name)
+	router.HandleFunc(pathLoggers, ca.getLoggers).Methods("GET")
+	router.HandleFunc(pathLoggers, ca.putLoggers).Methods("PUT")
+	router.HandleFunc(pathLoggers+"/{logger}", ca.putLogger).Methods("PUT")
+	pathComponent := fmt.Sprintf("/%s/components/{component}", ca.containerDaemon.Name)
+	router.HandleFunc(pathComponent+"/history", ca.getHistory).Methods("GET")
+	router.HandleFunc(pathComponent+"/logs", ca.getLogs).Methods("GET")
+	pathAction := fmt.Sprintf("%s/{action:%s}", pathComponent, strings.Join(lifecycleservice.Actions, "|"))
+	router.HandleFunc(pathAction, ca.postAction).Methods("POST")
 	httpServer := &http.Server{
 		Addr:    fmt.Sprintf(":%v", ca.containerDaemon.TransportSettings.Port),
 		Handler: router,
@@ -77,8 +156,13 @@ func (ca *ContainerAgent) Start() {
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGINT)
 
+	// reload configuration on SIGHUP
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+
 	// heart beat timer
-	hearBeatTimer := time.NewTicker(HeartBeatInterval)
+	heartBeatInterval := ca.containerDaemon.Lifecycle.GetHeartBeatInterval()
+	hearBeatTimer := time.NewTicker(heartBeatInterval)
 
 	// exit channel
 	exitChan := make(chan int)
@@ -98,6 +182,22 @@ func (ca *ContainerAgent) Start() {
 				if !ca.LifecycleServices.CheckState() {
 					log.Error("CheckState FAIL")
 				}
+				ca.saveState()
+
+			case req := <-ca.controlChan:
+				ca.control(req)
+
+			case <-hupChan:
+				log.Infoln("Received SIGHUP")
+				ca.RequestConfigReload()
+
+			case <-ca.reloadChan:
+				ca.reloadConfig()
+				if interval := ca.containerDaemon.Lifecycle.GetHeartBeatInterval(); interval != heartBeatInterval {
+					heartBeatInterval = interval
+					hearBeatTimer.Stop()
+					hearBeatTimer = time.NewTicker(heartBeatInterval)
+				}
 
 			case <-signalChan:
 				log.Infoln("Received os interrupt")
@@ -122,16 +222,49 @@ func (ca *ContainerAgent) Start() {
 
 // ModelCA model container agent
 type ModelCA struct {
-	Name   string `json:"name"`
-	Status string `json:status`
+	Name       string                             `json:"name"`
+	Status     string                             `json:"status"`
+	Policy     string                             `json:"policy"`
+	Components []lifecycleservice.ComponentStatus `json:"components"`
 }
 
 // REST API
 func (ca *ContainerAgent) getStatus(w http.ResponseWriter, r *http.Request) {
 	mca := &ModelCA{
-		Name:   ca.containerDaemon.Name,
-		Status: "UNKNOWN",
+		Name:       ca.daemonConfig().Name,
+		Status:     ca.LifecycleServices.Status(),
+		Policy:     ca.LifecycleServices.Policy(),
+		Components: ca.LifecycleServices.ComponentStatuses(),
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(mca)
 }
+
+// build metadata of the agent
+func (ca *ContainerAgent) getVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(version.Get())
+}
+
+// readiness probe, ready only when the aggregate container status is ACTIVE
+func (ca *ContainerAgent) getReady(w http.ResponseWriter, r *http.Request) {
+	status := ca.LifecycleServices.Status()
+	if status != "ACTIVE" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	fmt.Fprintln(w, status)
+}
+
+// state transitions of a managed component, oldest first
+func (ca *ContainerAgent) getHistory(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["component"]
+	history, ok := ca.LifecycleServices.History(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown component [%s]", name), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
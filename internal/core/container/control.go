@@ -0,0 +1,158 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rameshpolishetti/mlca/internal/core/service/lifecycleservice"
+)
+
+// DefaultLogTail default number of lines of the component output returned by the logs api
+const DefaultLogTail = 100
+
+// controlTimeout time an action requested through the api waits for the lifecycle loop to take it
+var controlTimeout = 10 * time.Second
+
+// controlRequest action requested for a component, applied by the lifecycle loop between heartbeats
+type controlRequest struct {
+	component string
+	action    string
+	result    chan controlResult
+}
+
+type controlResult struct {
+	status lifecycleservice.ComponentStatus
+	err    error
+}
+
+// control applies a control request and persists the resulting state
+func (ca *ContainerAgent) control(req controlRequest) {
+	status, err := ca.LifecycleServices.Control(req.component, req.action)
+	req.result <- controlResult{status: status, err: err}
+	ca.saveState()
+}
+
+// postAction requests an action for a component, e.g. POST /mashling/components/TMG-LFA/restart,
+// and returns the status of the component after it was applied. The action is not applied when
+// the lifecycle loop does not take it within controlTimeout or the client is gone before.
+func (ca *ContainerAgent) postAction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	req := controlRequest{
+		component: vars["component"],
+		action:    vars["action"],
+		result:    make(chan controlResult, 1),
+	}
+	// the lifecycle loop takes the request between heartbeats, it may be busy with a reload or a slow stop
+	timer := time.NewTimer(controlTimeout)
+	defer timer.Stop()
+	select {
+	case ca.controlChan <- req:
+	case <-r.Context().Done():
+		return
+	case <-timer.C:
+		http.Error(w, fmt.Sprintf("agent is busy, %s of [%s] was not applied, try again", req.action, req.component), http.StatusServiceUnavailable)
+		return
+	}
+	res := <-req.result
+
+	if res.err == lifecycleservice.ErrUnknownComponent {
+		http.Error(w, fmt.Sprintf("unknown component [%s]", req.component), http.StatusNotFound)
+		return
+	}
+	if _, ok := res.err.(*lifecycleservice.IllegalTransitionError); ok {
+		http.Error(w, res.err.Error(), http.StatusConflict)
+		return
+	}
+	if res.err != nil {
+		http.Error(w, res.err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Infof("%s of [%s] requested through the api", req.action, req.component)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res.status)
+}
+
+// getLogs returns the last lines of the captured output of a component, ?tail=0 returns all of it
+func (ca *ContainerAgent) getLogs(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["component"]
+	if _, ok := ca.LifecycleServices.History(name); !ok {
+		http.Error(w, fmt.Sprintf("unknown component [%s]", name), http.StatusNotFound)
+		return
+	}
+
+	tail := DefaultLogTail
+	if t := r.URL.Query().Get("tail"); t != "" {
+		n, err := strconv.Atoi(t)
+		if err != nil || n < 0 {
+			http.Error(w, fmt.Sprintf("invalid tail %s", t), http.StatusBadRequest)
+			return
+		}
+		tail = n
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	f, err := os.Open(ca.daemonConfig().ComponentLogFile(name))
+	if os.IsNotExist(err) {
+		// nothing captured yet
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	offset, err := tailOffset(f, tail)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	f.Seek(offset, io.SeekStart)
+	io.Copy(w, f)
+}
+
+// tailOffset returns the offset of the last n lines of a file, reading it backwards
+func tailOffset(f *os.File, n int) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	if n == 0 || size == 0 {
+		return 0, nil
+	}
+
+	buf := make([]byte, 4096)
+	lines := 0
+	end := size
+	for end > 0 {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		chunk := buf[:end-start]
+		_, err := f.ReadAt(chunk, start)
+		if err != nil {
+			return 0, err
+		}
+		for i := len(chunk) - 1; i >= 0; i-- {
+			// the newline ending the last line does not start a line
+			if chunk[i] != '\n' || start+int64(i) == size-1 {
+				continue
+			}
+			lines++
+			if lines == n {
+				return start + int64(i) + 1, nil
+			}
+		}
+		end = start
+	}
+	return 0, nil
+}
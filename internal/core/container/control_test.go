@@ -0,0 +1,173 @@
+package container
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/rameshpolishetti/mlca/internal/core/service/lifecycleservice"
+)
+
+// knownComponents lifecycle services knowing the history of the named components only
+type knownComponents struct {
+	lifecycleservice.LifeCycleServices
+	names []string
+}
+
+func (kc knownComponents) History(name string) ([]lifecycleservice.Transition, bool) {
+	for _, n := range kc.names {
+		if n == name {
+			return nil, true
+		}
+	}
+	return nil, false
+}
+
+func newTestAgent(t *testing.T) (*ContainerAgent, *mux.Router, func()) {
+	dir, err := ioutil.TempDir("", "cagent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &ContainerAgent{
+		containerDaemon:   config.ContainerDaemon{Name: "mashling", LogDir: dir},
+		controlChan:       make(chan controlRequest),
+		LifecycleServices: knownComponents{names: []string{"TMG-LFA", "TMG-Microgateway"}},
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/mashling/components/{component}/logs", ca.getLogs).Methods("GET")
+	router.HandleFunc("/mashling/components/{component}/{action}", ca.postAction).Methods("POST")
+	return ca, router, func() { os.RemoveAll(dir) }
+}
+
+func TestGetLogs(t *testing.T) {
+	ca, router, cleanup := newTestAgent(t)
+	defer cleanup()
+
+	get := func(path string) (int, string) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	// nothing captured before the component was launched
+	if code, body := get("/mashling/components/TMG-LFA/logs"); code != http.StatusOK || body != "" {
+		t.Errorf("logs before launch = %d %q, want an empty 200", code, body)
+	}
+
+	var lines []string
+	for i := 0; i < 150; i++ {
+		lines = append(lines, strings.Repeat("x", i*40))
+	}
+	output := strings.Join(lines, "\n") + "\n"
+	err := ioutil.WriteFile(filepath.Join(ca.containerDaemon.LogDir, "TMG-LFA.log"), []byte(output), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, body := get("/mashling/components/TMG-LFA/logs"); body != strings.Join(lines[50:], "\n")+"\n" {
+		t.Errorf("default tail returned %d lines, want the last %d", strings.Count(body, "\n"), DefaultLogTail)
+	}
+	if _, body := get("/mashling/components/TMG-LFA/logs?tail=2"); body != lines[148]+"\n"+lines[149]+"\n" {
+		t.Errorf("tail=2 returned %d lines, want the last 2", strings.Count(body, "\n"))
+	}
+	if _, body := get("/mashling/components/TMG-LFA/logs?tail=0"); body != output {
+		t.Errorf("tail=0 returned %d lines, want all 150", strings.Count(body, "\n"))
+	}
+	if _, body := get("/mashling/components/TMG-LFA/logs?tail=500"); body != output {
+		t.Errorf("tail=500 returned %d lines, want all 150", strings.Count(body, "\n"))
+	}
+	if code, _ := get("/mashling/components/TMG-LFA/logs?tail=-1"); code != http.StatusBadRequest {
+		t.Errorf("tail=-1 = %d, want %d", code, http.StatusBadRequest)
+	}
+	if code, _ := get("/mashling/components/TMG-GW/logs"); code != http.StatusNotFound {
+		t.Errorf("logs of an unknown component = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestTailOffsetWithoutTrailingNewline(t *testing.T) {
+	f, err := ioutil.TempFile("", "tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	f.WriteString("a\n\nb\nc")
+
+	for n, want := range map[int]string{1: "c", 2: "b\nc", 3: "\nb\nc", 4: "a\n\nb\nc"} {
+		offset, err := tailOffset(f, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := "a\n\nb\nc"[offset:]; got != want {
+			t.Errorf("last %d lines = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestPostAction(t *testing.T) {
+	ca, router, cleanup := newTestAgent(t)
+	defer cleanup()
+
+	post := func(ctx context.Context, path string) int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("POST", path, nil).WithContext(ctx))
+		return rec.Code
+	}
+
+	// the lifecycle loop applies the requests it takes
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			req := <-ca.controlChan
+			switch req.component {
+			case "TMG-LFA":
+				req.result <- controlResult{status: lifecycleservice.ComponentStatus{Name: req.component, State: "STOPPED"}}
+			case "TMG-Microgateway":
+				req.result <- controlResult{err: &lifecycleservice.IllegalTransitionError{Component: req.component, Action: req.action, State: "DISABLED"}}
+			default:
+				req.result <- controlResult{err: lifecycleservice.ErrUnknownComponent}
+			}
+		}
+	}()
+	for path, want := range map[string]int{
+		"/mashling/components/TMG-LFA/stop":          http.StatusOK,
+		"/mashling/components/TMG-Microgateway/stop": http.StatusConflict,
+		"/mashling/components/TMG-GW/stop":           http.StatusNotFound,
+	} {
+		if code := post(context.Background(), path); code != want {
+			t.Errorf("POST %s = %d, want %d", path, code, want)
+		}
+	}
+	<-done
+
+	// while the loop is busy the request is not taken
+	defer func(timeout time.Duration) { controlTimeout = timeout }(controlTimeout)
+	controlTimeout = 20 * time.Millisecond
+	if code := post(context.Background(), "/mashling/components/TMG-LFA/restart"); code != http.StatusServiceUnavailable {
+		t.Errorf("POST to a busy agent = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	// nor once the client is gone
+	controlTimeout = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	post(ctx, "/mashling/components/TMG-LFA/restart")
+	if time.Since(start) > time.Second {
+		t.Errorf("the request of a client that is gone waited for the loop")
+	}
+	select {
+	case req := <-ca.controlChan:
+		t.Errorf("%s was requested after the client was gone", req.action)
+	default:
+	}
+}
@@ -0,0 +1,126 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/rameshpolishetti/mlca/logger"
+)
+
+// ConfigureLogging applies the logging settings of the container configuration to all loggers
+func ConfigureLogging(cd config.ContainerDaemon) error {
+	ls := cd.Logging
+	err := logger.SetFormat(ls.Format)
+	if err != nil {
+		return err
+	}
+	err = logger.SetLevels(ls.Level)
+	if err != nil {
+		return err
+	}
+	logger.SetUTC(ls.UTC)
+	logger.SetReportCaller(ls.Caller)
+
+	sinks := make([]*logger.Sink, 0, len(ls.Sinks))
+	for i, s := range ls.Sinks {
+		options := logger.SinkOptions{
+			Type:               s.Type,
+			Level:              s.Level,
+			Format:             s.Format,
+			Path:               s.Path,
+			MaxSize:            s.MaxSize,
+			MaxBackups:         s.MaxBackups,
+			Network:            s.Network,
+			Address:            s.Address,
+			Tag:                s.Tag,
+			Facility:           s.Facility,
+			CAFile:             s.CAFile,
+			InsecureSkipVerify: s.InsecureSkipVerify,
+		}
+		if s.Component != "" {
+			options.Address = forwardAddress(cd, s.Component)
+		}
+
+		sink, err := logger.NewSink(options)
+		if err != nil {
+			for _, created := range sinks {
+				created.Close()
+			}
+			return fmt.Errorf("logging.sinks[%d]: %s", i, err)
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) == 0 {
+		sink, _ := logger.NewSink(logger.SinkOptions{Type: logger.SinkStdout})
+		sinks = append(sinks, sink)
+	}
+	logger.SetSinks(sinks)
+	return nil
+}
+
+// forwardAddress returns the local address a managed component receives forwarded logs on
+func forwardAddress(cd config.ContainerDaemon, name string) string {
+	for _, mc := range cd.Components {
+		if mc.Name != name {
+			continue
+		}
+		if port, ok := cd.Logging.ForwardPort(mc); ok {
+			return fmt.Sprintf("127.0.0.1:%d", port)
+		}
+	}
+	return fmt.Sprintf("127.0.0.1:%d", config.DefaultForwardPort)
+}
+
+// loggerLevels model of the logger levels api
+type loggerLevels struct {
+	Level   string            `json:"level"`
+	Loggers map[string]string `json:"loggers,omitempty"`
+}
+
+// getLoggers returns the global level and the effective level of every logger
+func (ca *ContainerAgent) getLoggers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loggerLevels{
+		Level:   logger.Level(),
+		Loggers: logger.Levels(),
+	})
+}
+
+// putLoggers replaces all levels with a level specification, e.g. {"level": "info,jsonclient=warn"}
+func (ca *ContainerAgent) putLoggers(w http.ResponseWriter, r *http.Request) {
+	levels := loggerLevels{}
+	err := json.NewDecoder(r.Body).Decode(&levels)
+	if err == nil {
+		err = logger.SetLevels(levels.Level)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Infof("log levels changed to [%s]", levels.Level)
+	ca.getLoggers(w, r)
+}
+
+// putLogger changes the level of a single logger, e.g. {"level": "debug"}
+func (ca *ContainerAgent) putLogger(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["logger"]
+	if _, ok := logger.Levels()[name]; !ok {
+		http.Error(w, fmt.Sprintf("unknown logger [%s]", name), http.StatusNotFound)
+		return
+	}
+
+	levels := loggerLevels{}
+	err := json.NewDecoder(r.Body).Decode(&levels)
+	if err == nil {
+		err = logger.SetLoggerLevel(name, levels.Level)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Infof("log level of [%s] changed to %s", name, levels.Level)
+	ca.getLoggers(w, r)
+}
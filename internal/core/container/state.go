@@ -0,0 +1,66 @@
+package container
+
+import (
+	"reflect"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/statefile"
+)
+
+// restoreState resumes the registration and the components persisted by a previous agent run
+func (ca *ContainerAgent) restoreState() {
+	stateFile := ca.containerDaemon.GetStateFile()
+	s, err := statefile.Load(stateFile)
+	if err != nil {
+		log.Errorf("unable to read state file %s: %s", stateFile, err)
+		return
+	}
+	if s == nil {
+		return
+	}
+	if !s.Matches(ca.containerDaemon) {
+		log.Infof("state file %s belongs to another container, starting afresh", stateFile)
+		return
+	}
+
+	log.Infof("resuming the state saved at %s, registration %s", s.SavedTime, s.TmgcID)
+	ca.RegService.Restore(s.TmgcID, s.ZoneID, s.ClusterID)
+	ca.LifecycleServices.Resume(s)
+}
+
+// saveState persists the registration and the component states whenever they change
+func (ca *ContainerAgent) saveState() {
+	ci := ca.RegService.ContainerInstance()
+	if ci.TmgcID == "" {
+		// nothing to resume before the container is registered
+		return
+	}
+
+	s := statefile.State{
+		Name:          ca.containerDaemon.Name,
+		ComponentType: ca.containerDaemon.ComponentType,
+		Cluster:       ca.containerDaemon.Cluster,
+		Zone:          ca.containerDaemon.Zone,
+		TmgcID:        ci.TmgcID,
+		ZoneID:        ci.ZoneID,
+		ClusterID:     ci.ClusterID,
+	}
+	for _, mService := range ca.LifecycleServices.Components() {
+		s.Components = append(s.Components, statefile.Component{
+			Name:        mService.Name(),
+			ComponentID: mService.ComponentID(),
+			Pid:         mService.Pid(),
+			State:       mService.State(),
+		})
+	}
+	if reflect.DeepEqual(s, ca.savedState) {
+		return
+	}
+
+	stateFile := ca.containerDaemon.GetStateFile()
+	err := statefile.Save(stateFile, s)
+	if err != nil {
+		log.Errorf("unable to write state file %s: %s", stateFile, err)
+		return
+	}
+	ca.savedState = s
+}
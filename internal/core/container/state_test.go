@@ -0,0 +1,133 @@
+package container
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/rameshpolishetti/mlca/internal/core/common/statefile"
+	"github.com/rameshpolishetti/mlca/internal/core/service"
+	"github.com/rameshpolishetti/mlca/internal/core/service/lifecycleservice"
+)
+
+// resumedComponents lifecycle services without components, recording the state they were asked to resume
+type resumedComponents struct {
+	lifecycleservice.LifeCycleServices
+	resumed *statefile.State
+}
+
+func (rc *resumedComponents) Resume(s *statefile.State) { rc.resumed = s }
+
+func (rc *resumedComponents) Components() []lifecycleservice.LifeCycleService { return nil }
+
+func TestRestoreState(t *testing.T) {
+	// registry still knowing the registration tm-1 of the previous run
+	var posted int32
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/registry/rest/v1")
+		switch {
+		case path == "/status":
+			fmt.Fprint(w, `{"status":"REGISTRY_READY"}`)
+		case r.Method == http.MethodGet && path == "/clusters/cluster-1/zones/zone-1/trafficmanagers/tm-1":
+			fmt.Fprint(w, `{"tmgcId":"tm-1"}`)
+		case r.Method == http.MethodPost:
+			atomic.AddInt32(&posted, 1)
+			fmt.Fprint(w, `{"tmgcId":"tm-2","zoneId":"zone-1","clusterId":"cluster-1","status":"registered"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer registry.Close()
+
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cd := config.ContainerDaemon{
+		Name:          "mashling",
+		ComponentType: "trafficmanagers",
+		Cluster:       "cluster",
+		Zone:          "zone",
+		Inboxes:       map[string]string{"registry": registry.URL},
+		StateFile:     filepath.Join(dir, "mashling.state.json"),
+	}
+	saved := statefile.State{
+		Name:          "mashling",
+		ComponentType: "trafficmanagers",
+		Cluster:       "cluster",
+		Zone:          "zone",
+		TmgcID:        "tm-1",
+		ZoneID:        "zone-1",
+		ClusterID:     "cluster-1",
+		Components:    []statefile.Component{{Name: "TMG-LFA", ComponentID: "component-1", Pid: 4242, State: "ACTIVE"}},
+	}
+
+	gone := saved
+	gone.TmgcID = "tm-3"
+
+	tests := []struct {
+		name  string
+		saved *statefile.State
+		// written as is when there is no saved state
+		raw          string
+		wantRestored bool
+	}{
+		{name: "no state file"},
+		{name: "unreadable state file", raw: "{"},
+		{name: "state of another container", raw: `{"name": "other", "componentType": "trafficmanagers", "cluster": "cluster", "zone": "zone", "tmgcId": "tm-9"}`},
+		{name: "registration of the previous run", saved: &saved, wantRestored: true},
+		{name: "registration removed from registry", saved: &gone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(cd.StateFile)
+			switch {
+			case tt.saved != nil:
+				if err := statefile.Save(cd.StateFile, *tt.saved); err != nil {
+					t.Fatal(err)
+				}
+			case tt.raw != "":
+				if err := ioutil.WriteFile(cd.StateFile, []byte(tt.raw), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			atomic.StoreInt32(&posted, 0)
+			lcServices := &resumedComponents{}
+			ca := &ContainerAgent{containerDaemon: cd, RegService: service.NewRegistryProxyService(cd), LifecycleServices: lcServices}
+
+			ca.restoreState()
+			if !ca.RegService.Register() {
+				t.Fatal("registration failed")
+			}
+
+			// the components of a matching state are resumed, they drop their registrations with the container one
+			if tt.saved == nil && lcServices.resumed != nil {
+				t.Errorf("resumed %+v", lcServices.resumed)
+			}
+			if tt.saved != nil && (lcServices.resumed == nil || len(lcServices.resumed.Components) != 1 || lcServices.resumed.Components[0].Pid != 4242) {
+				t.Errorf("resumed %+v, want the saved components", lcServices.resumed)
+			}
+			registrations := atomic.LoadInt32(&posted)
+			if restored := ca.RegService.RegistrationRestored(); restored != tt.wantRestored || (registrations == 0) != tt.wantRestored {
+				t.Errorf("registration restored = %v after %d registrations, want %v", restored, registrations, tt.wantRestored)
+			}
+
+			// the registration in use is saved
+			ca.saveState()
+			s, err := statefile.Load(cd.StateFile)
+			if want := ca.RegService.ContainerInstance().TmgcID; err != nil || s == nil || s.TmgcID != want || !s.Matches(cd) {
+				t.Errorf("saved state = %+v, %v, want registration %s", s, err, want)
+			}
+		})
+	}
+}
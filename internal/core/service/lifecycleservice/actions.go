@@ -0,0 +1,38 @@
+package lifecycleservice
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// ActionStart starts a stopped component again
+	ActionStart = "start"
+	// ActionStop stops the component until it is started again
+	ActionStop = "stop"
+	// ActionRestart stops the component and runs its lifecycle again
+	ActionRestart = "restart"
+	// ActionReload rebuilds the configuration of an ACTIVE component and reloads it
+	ActionReload = "reload"
+	// ActionDisable stops the component until it is enabled again, also across agent restarts
+	ActionDisable = "disable"
+	// ActionEnable starts a disabled component again
+	ActionEnable = "enable"
+)
+
+// Actions actions that can be requested for a component through the agent api
+var Actions = []string{ActionStart, ActionStop, ActionRestart, ActionReload, ActionDisable, ActionEnable}
+
+// ErrUnknownComponent no managed component has the requested name
+var ErrUnknownComponent = errors.New("unknown component")
+
+// IllegalTransitionError an action is not allowed in the current state of the component
+type IllegalTransitionError struct {
+	Component string
+	Action    string
+	State     string
+}
+
+func (e *IllegalTransitionError) Error() string {
+	return fmt.Sprintf("%s of [%s] is not allowed in state %s", e.Action, e.Component, e.State)
+}
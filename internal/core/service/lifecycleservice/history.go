@@ -0,0 +1,92 @@
+package lifecycleservice
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+)
+
+const (
+	// TriggerHeartbeat transition made by the lifecycle reconciliation
+	TriggerHeartbeat = "heartbeat"
+	// TriggerProbe transition caused by a failed liveness or health probe
+	TriggerProbe = "probe"
+	// TriggerAPI transition requested through the agent api
+	TriggerAPI = "api"
+	// TriggerConfig transition caused by a configuration change
+	TriggerConfig = "config"
+)
+
+// Transition state transition of a managed component
+type Transition struct {
+	Timestamp time.Time `json:"timestamp"`
+	Component string    `json:"component"`
+	Event     string    `json:"event"`
+	Src       string    `json:"src"`
+	Dst       string    `json:"dst"`
+	Trigger   string    `json:"trigger"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// History bounded history of the state transitions of a managed component, oldest first
+type History struct {
+	mutex       sync.RWMutex
+	transitions []Transition
+}
+
+// NewHistory creates an empty history
+func NewHistory() *History {
+	return &History{}
+}
+
+// Add appends a transition, dropping the oldest ones beyond size
+func (h *History) Add(t Transition, size int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.transitions = append(h.transitions, t)
+	if len(h.transitions) > size {
+		h.transitions = append([]Transition(nil), h.transitions[len(h.transitions)-size:]...)
+	}
+}
+
+// Transitions returns a copy of the recorded transitions
+func (h *History) Transitions() []Transition {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return append([]Transition{}, h.transitions...)
+}
+
+// Record adds a transition, keeping the configured number of transitions, and appends it to the audit file if any
+func (h *History) Record(t Transition, settings config.LifecycleSettings) error {
+	h.Add(t, settings.GetHistorySize())
+	if settings.AuditFile == "" {
+		return nil
+	}
+	return appendAudit(settings.AuditFile, t)
+}
+
+// guards appends to the audit file, shared by all components
+var auditLock sync.Mutex
+
+// appendAudit appends a transition to the audit file as a json line
+func appendAudit(filename string, t Transition) error {
+	line, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	auditLock.Lock()
+	defer auditLock.Unlock()
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package lifecycleservice
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+)
+
+func TestHistoryKeepsTheLatestTransitions(t *testing.T) {
+	h := NewHistory()
+	for i := 0; i < 5; i++ {
+		h.Add(Transition{Event: fmt.Sprintf("event-%d", i)}, 3)
+	}
+
+	transitions := h.Transitions()
+	if len(transitions) != 3 || transitions[0].Event != "event-2" || transitions[2].Event != "event-4" {
+		t.Fatalf("transitions = %+v, want event-2 to event-4", transitions)
+	}
+	transitions[0].Event = "changed"
+	if h.Transitions()[0].Event != "event-2" {
+		t.Error("Transitions() returned the recorded transitions instead of a copy")
+	}
+}
+
+func TestHistoryRecordsTransitions(t *testing.T) {
+	registry := newTestRegistry()
+	defer registry.Close()
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lcs, fc := newTestService(registry, config.ManagedComponent{Name: "TMG-LFA", HealthFailureThreshold: 1})
+	lcs.settings = &config.LifecycleSettings{HistorySize: 6, AuditFile: filepath.Join(dir, "audit.log")}
+	heartbeats(t, lcs, "ACTIVE", 5)
+	fc.healthy = false
+	heartbeats(t, lcs, "UNKNOWN", 1)
+	if err := lcs.Control(ActionStop); err != nil {
+		t.Fatal(err)
+	}
+
+	// monitoring an ACTIVE component is not a transition
+	want := []string{
+		"UNKNOWN -> UNSATISFIED (heartbeat)",
+		"UNSATISFIED -> RESOLVED (heartbeat)",
+		"RESOLVED -> STANDBY (heartbeat)",
+		"STANDBY -> ACTIVE (heartbeat)",
+		"ACTIVE -> UNKNOWN (probe) failed 1 consecutive health probes",
+		"UNKNOWN -> STOPPED (api)",
+	}
+	history := lcs.History()
+	if len(history) != len(want) {
+		t.Fatalf("recorded %d transitions, want %d: %+v", len(history), len(want), history)
+	}
+	for i := range want {
+		if got := lastTransition(history[:i+1]); got != want[i] {
+			t.Errorf("transition %d = %q, want %q", i, got, want[i])
+		}
+		if history[i].Component != "TMG-LFA" || history[i].Timestamp.IsZero() {
+			t.Errorf("transition %d = %+v, want the component and time", i, history[i])
+		}
+	}
+
+	// the audit file has every transition as a json line, also those beyond the history size
+	lcs.Control(ActionStart)
+	f, err := os.Open(lcs.settings.AuditFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var audited []Transition
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var transition Transition
+		if err := json.Unmarshal(scanner.Bytes(), &transition); err != nil {
+			t.Fatalf("audit line %q: %s", scanner.Text(), err)
+		}
+		audited = append(audited, transition)
+	}
+	if len(audited) != len(want)+1 || audited[4].Error != "failed 1 consecutive health probes" || audited[6].Event != ActionStart {
+		t.Errorf("audited %+v", audited)
+	}
+	if history := lcs.History(); len(history) != 6 || history[5].Event != ActionStart {
+		t.Errorf("history = %+v, want the last 6 transitions", history)
+	}
+}
@@ -1,28 +1,86 @@
 package lifecycleservice
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
 	"github.com/looplab/fsm"
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/rameshpolishetti/mlca/internal/core/common/statefile"
+	"github.com/rameshpolishetti/mlca/internal/core/common/util"
 	"github.com/rameshpolishetti/mlca/internal/core/component"
 	"github.com/rameshpolishetti/mlca/internal/core/service"
+	"github.com/rameshpolishetti/mlca/logger"
+	"github.com/sirupsen/logrus"
 )
 
 // LifeCycleService LifeCycleService
 type LifeCycleService interface {
 	CheckState() bool
+	Name() string
+	State() string
+	Status() ComponentStatus
+	Config() config.ManagedComponent
+	ComponentID() string
+	SetComponentID(componentID string)
+	Pid() int
+	Resume(c statefile.Component)
+	History() []Transition
+	Control(action string) error
+	Stop(deregister bool) bool
 }
 
 // LifeCycleServiceImpl LifeCycleServiceImpl
 type LifeCycleServiceImpl struct {
 	FSM        *fsm.FSM
+	mcConfig   config.ManagedComponent
 	mComponent component.Component
+	log        *logrus.Logger
 	regService *service.RegistryProxy
+	settings   *config.LifecycleSettings
+	// stateOf returns current state of another managed component
+	stateOf func(name string) string
+
+	// state transitions, kept across restarts of the component on configuration reload
+	history *History
+	// what caused the event being fired, recorded with its transition
+	trigger string
+	cause   error
+
+	// id assigned by registry to the component
+	componentID string
+	// state the lifecycle is replayed up to on the next heartbeat, after adopting the process of a previous agent run
+	resumeTo string
+
+	// reload in progress: what triggered it, configuration file before the reload, and deadline for the component to become healthy
+	reloadTrigger  string
+	reloadApplied  bool
+	rolledBack     bool
+	configBackup   []byte
+	reloadDeadline time.Time
+
+	// consecutive failed health probes of the ACTIVE component
+	probeFailures int
+
+	// time the current state was entered
+	stateEnteredAt time.Time
+	// state and time the status was last published to the registry
+	publishedState  string
+	statusUpdatedAt time.Time
 }
 
-// NewLifeCycleService New
-func NewLifeCycleService(mc component.Component, rService *service.RegistryProxy) LifeCycleService {
+// NewLifeCycleService creates the managed component with its factory and the lifecycle service managing it,
+// the component is given a contextual logger named after it and its transitions are recorded in history
+func NewLifeCycleService(mcConfig config.ManagedComponent, factory component.Factory, s component.Services, rService *service.RegistryProxy, settings *config.LifecycleSettings, history *History, stateOf func(name string) string) LifeCycleService {
 	lcServiceImpl := &LifeCycleServiceImpl{
-		mComponent: mc,
-		regService: rService,
+		mcConfig:       mcConfig,
+		regService:     rService,
+		settings:       settings,
+		stateOf:        stateOf,
+		history:        history,
+		stateEnteredAt: time.Now(),
 	}
 
 	/*
@@ -31,9 +89,16 @@ func NewLifeCycleService(mc component.Component, rService *service.RegistryProxy
 	* RESOLVED	activate()	launchComponent()
 	* STANDBY	standby()	prepareForActive()
 	* ACTIVE	monitor()	watchComponent()
-	* RELOAD	reload()
+	* RELOAD	reload()	buildConfiguration() reload()
 	* RECYCLE	waitingForDependencies()
 	* DISABLED	deavtivate()
+	* FAILED	fail()	state timeout exceeded or reload failed, restart()	restart delay expired
+	*
+	* actions requested through the agent api
+	* STOPPED	stop()	stop(), kept until start()
+	* DISABLED	disable()	stop(), kept across agent restarts until enable()
+	* UNKNOWN	start() enable() restart()	stop(), then the lifecycle starts over keeping the registration
+	* RELOAD	reload()
 	 */
 
 	lcServiceImpl.FSM = fsm.NewFSM(
@@ -44,28 +109,293 @@ func NewLifeCycleService(mc component.Component, rService *service.RegistryProxy
 			{Name: "activate", Src: []string{"RESOLVED"}, Dst: "STANDBY"},
 			{Name: "standby", Src: []string{"STANDBY"}, Dst: "ACTIVE"},
 			{Name: "monitor", Src: []string{"ACTIVE"}, Dst: "ACTIVE"},
+			{Name: "reload", Src: []string{"ACTIVE"}, Dst: "RELOAD"},
+			{Name: "reloaded", Src: []string{"RELOAD"}, Dst: "ACTIVE"},
 			{Name: "deavtivate", Src: []string{"ACTIVE"}, Dst: "UNKNOWN"},
+			{Name: "fail", Src: []string{"UNKNOWN", "UNSATISFIED", "RESOLVED", "STANDBY", "ACTIVE", "RELOAD"}, Dst: "FAILED"},
+			{Name: ActionStop, Src: []string{"UNKNOWN", "UNSATISFIED", "RESOLVED", "STANDBY", "ACTIVE", "RELOAD", "FAILED"}, Dst: "STOPPED"},
+			{Name: ActionStart, Src: []string{"STOPPED"}, Dst: "UNKNOWN"},
+			{Name: ActionRestart, Src: []string{"UNSATISFIED", "RESOLVED", "STANDBY", "ACTIVE", "RELOAD", "FAILED"}, Dst: "UNKNOWN"},
+			{Name: ActionDisable, Src: []string{"UNKNOWN", "UNSATISFIED", "RESOLVED", "STANDBY", "ACTIVE", "RELOAD", "FAILED", "STOPPED"}, Dst: "DISABLED"},
+			{Name: ActionEnable, Src: []string{"DISABLED"}, Dst: "UNKNOWN"},
 		},
 		fsm.Callbacks{
 			"enter_state": func(e *fsm.Event) { lcServiceImpl.enterState(e) },
 		},
 	)
+
+	lcServiceImpl.log = logger.GetContextLogger(mcConfig.Name, lcServiceImpl.logContext)
+	s.Log = lcServiceImpl.log
+	lcServiceImpl.mComponent = factory(mcConfig, s)
 	return lcServiceImpl
 }
 
+// logContext returns the fields every line of the component logger is tagged with
+func (lcServiceImpl *LifeCycleServiceImpl) logContext() logrus.Fields {
+	return logrus.Fields{
+		"component":   lcServiceImpl.mcConfig.Name,
+		"qualifier":   lcServiceImpl.mcConfig.Qualifier,
+		"tmgcId":      lcServiceImpl.mcConfig.ContainerInstance.TmgcID,
+		"componentId": lcServiceImpl.componentID,
+		"state":       lcServiceImpl.FSM.Current(),
+	}
+}
+
 // CheckState CheckState
 func (lcServiceImpl *LifeCycleServiceImpl) enterState(e *fsm.Event) {
-	log.Debugf("%s -> %s", e.Src, e.Dst)
+	lcServiceImpl.log.Debugf("%s -> %s", e.Src, e.Dst)
+	if e.Src != e.Dst {
+		lcServiceImpl.stateEnteredAt = time.Now()
+		lcServiceImpl.probeFailures = 0
+		lcServiceImpl.record(e.Event, e.Src, e.Dst)
+	}
+}
+
+// fire fires an event, recording what triggered it and the error that caused it with the transition
+func (lcServiceImpl *LifeCycleServiceImpl) fire(event string, trigger string, cause error) error {
+	lcServiceImpl.trigger = trigger
+	lcServiceImpl.cause = cause
+	defer func() {
+		lcServiceImpl.trigger = ""
+		lcServiceImpl.cause = nil
+	}()
+	return lcServiceImpl.FSM.Event(event)
+}
+
+// setState moves the component to a state outside of the defined events, e.g. to relaunch it
+func (lcServiceImpl *LifeCycleServiceImpl) setState(state string, event string, trigger string, cause error) {
+	src := lcServiceImpl.FSM.Current()
+	lcServiceImpl.FSM.SetState(state)
+	lcServiceImpl.stateEnteredAt = time.Now()
+	lcServiceImpl.probeFailures = 0
+
+	lcServiceImpl.trigger = trigger
+	lcServiceImpl.cause = cause
+	lcServiceImpl.record(event, src, state)
+	lcServiceImpl.trigger = ""
+	lcServiceImpl.cause = nil
+}
+
+// record adds a transition to the history and the audit file
+func (lcServiceImpl *LifeCycleServiceImpl) record(event, src, dst string) {
+	t := Transition{
+		Timestamp: time.Now(),
+		Component: lcServiceImpl.mcConfig.Name,
+		Event:     event,
+		Src:       src,
+		Dst:       dst,
+		Trigger:   lcServiceImpl.trigger,
+	}
+	if t.Trigger == "" {
+		t.Trigger = TriggerHeartbeat
+	}
+	if lcServiceImpl.cause != nil {
+		t.Error = lcServiceImpl.cause.Error()
+	}
+	err := lcServiceImpl.history.Record(t, *lcServiceImpl.settings)
+	if err != nil {
+		lcServiceImpl.log.Errorf("unable to write audit file: %s", err)
+	}
+}
+
+// History returns the recorded state transitions of the component, oldest first
+func (lcServiceImpl *LifeCycleServiceImpl) History() []Transition {
+	return lcServiceImpl.history.Transitions()
+}
+
+// Name returns managed component name
+func (lcServiceImpl *LifeCycleServiceImpl) Name() string {
+	return lcServiceImpl.mcConfig.Name
+}
+
+// State returns current state of the managed component
+func (lcServiceImpl *LifeCycleServiceImpl) State() string {
+	return lcServiceImpl.FSM.Current()
+}
+
+// Config returns managed component configuration
+func (lcServiceImpl *LifeCycleServiceImpl) Config() config.ManagedComponent {
+	return lcServiceImpl.mcConfig
+}
+
+// ComponentID returns id assigned by registry to the component
+func (lcServiceImpl *LifeCycleServiceImpl) ComponentID() string {
+	return lcServiceImpl.componentID
+}
+
+// SetComponentID sets the registry id of an already registered component, so it is not registered again
+func (lcServiceImpl *LifeCycleServiceImpl) SetComponentID(componentID string) {
+	lcServiceImpl.componentID = componentID
+}
+
+// Pid returns the pid of the component process, zero when it is not running
+func (lcServiceImpl *LifeCycleServiceImpl) Pid() int {
+	return lcServiceImpl.mComponent.Pid()
+}
+
+// Resume restores the component persisted by a previous agent run: its registration is reused and,
+// when its process is still running, the process is adopted and the lifecycle is replayed up to
+// ACTIVE within the next heartbeat instead of launching the process again. A process still running
+// in any other state, e.g. FAILED, is stopped so the lifecycle starting over does not launch it twice.
+func (lcServiceImpl *LifeCycleServiceImpl) Resume(c statefile.Component) {
+	lcServiceImpl.componentID = c.ComponentID
+	switch c.State {
+	case "STANDBY", "ACTIVE", "RELOAD":
+		if c.Pid > 0 && lcServiceImpl.mComponent.Adopt(c.Pid) {
+			lcServiceImpl.resumeTo = "ACTIVE"
+		}
+	default:
+		if c.Pid > 0 && lcServiceImpl.mComponent.Adopt(c.Pid) {
+			lcServiceImpl.log.Infof("stopping process %d of %s left running in state %s", c.Pid, lcServiceImpl.mcConfig.Name, c.State)
+			if !lcServiceImpl.mComponent.Stop() {
+				lcServiceImpl.log.Errorf("unable to stop %s", lcServiceImpl.mcConfig.Name)
+			}
+		}
+		if c.State == "DISABLED" {
+			lcServiceImpl.setState("DISABLED", "resume", TriggerHeartbeat, nil)
+		}
+	}
+}
+
+// resume replays the lifecycle of an adopted component up to the resumed state
+func (lcServiceImpl *LifeCycleServiceImpl) resume() bool {
+	target := lcServiceImpl.resumeTo
+	lcServiceImpl.resumeTo = ""
+
+	transitioned := false
+	for !lcServiceImpl.FSM.Is(target) && lcServiceImpl.switchState() {
+		transitioned = true
+	}
+	if lcServiceImpl.FSM.Is(target) {
+		lcServiceImpl.log.Infof("resumed %s in state %s", lcServiceImpl.mcConfig.Name, target)
+	} else {
+		lcServiceImpl.log.Infof("resumed %s up to state %s, continuing its lifecycle", lcServiceImpl.mcConfig.Name, lcServiceImpl.FSM.Current())
+	}
+
+	if transitioned {
+		lcServiceImpl.updateStatus()
+	}
+	return transitioned
+}
+
+// Control applies an action requested through the agent api, returning an IllegalTransitionError
+// when the action is not allowed in the current state. The process is stopped before the
+// component is stopped, disabled or restarted.
+func (lcServiceImpl *LifeCycleServiceImpl) Control(action string) error {
+	current := lcServiceImpl.FSM.Current()
+	if !lcServiceImpl.FSM.Can(action) {
+		return &IllegalTransitionError{Component: lcServiceImpl.mcConfig.Name, Action: action, State: current}
+	}
+
+	switch action {
+	case ActionReload:
+		lcServiceImpl.reloadTrigger = TriggerAPI
+	case ActionStop, ActionDisable, ActionRestart:
+		lcServiceImpl.resetReload()
+		lcServiceImpl.resumeTo = ""
+		if !lcServiceImpl.mComponent.Stop() {
+			return fmt.Errorf("unable to stop %s", lcServiceImpl.mcConfig.Name)
+		}
+	}
+
+	lcServiceImpl.log.Infof("[%s] requested in state %s", action, current)
+	err := lcServiceImpl.fire(action, TriggerAPI, nil)
+	if err != nil {
+		return err
+	}
+	lcServiceImpl.updateStatus()
+	return nil
+}
+
+// Stop stops the managed component, optionally removing it from registry
+func (lcServiceImpl *LifeCycleServiceImpl) Stop(deregister bool) bool {
+	result := lcServiceImpl.mComponent.Stop()
+	if deregister {
+		lcServiceImpl.regService.DeregisterComponent(lcServiceImpl.componentID)
+	}
+	return result
+}
+
+// Status returns status of the managed component for aggregation
+func (lcServiceImpl *LifeCycleServiceImpl) Status() ComponentStatus {
+	return ComponentStatus{
+		Name:     lcServiceImpl.mcConfig.Name,
+		State:    lcServiceImpl.FSM.Current(),
+		Critical: lcServiceImpl.mcConfig.Critical,
+		Optional: lcServiceImpl.mcConfig.Optional,
+	}
 }
 
 // CheckState CheckState
 func (lcServiceImpl *LifeCycleServiceImpl) CheckState() bool {
-	if lcServiceImpl.switchState() {
-		// update registry status
-		lcServiceImpl.regService.UpdateStatus(lcServiceImpl.FSM.Current())
-		return true
+	if lcServiceImpl.resumeTo != "" {
+		return lcServiceImpl.resume()
 	}
-	return false
+
+	result := lcServiceImpl.checkTimeout() || lcServiceImpl.switchState()
+
+	// update registry status on a state change, and periodically refresh it otherwise
+	if lcServiceImpl.FSM.Current() != lcServiceImpl.publishedState ||
+		time.Since(lcServiceImpl.statusUpdatedAt) >= lcServiceImpl.settings.GetStatusRefreshInterval() {
+		lcServiceImpl.updateStatus()
+	}
+	return result
+}
+
+func (lcServiceImpl *LifeCycleServiceImpl) updateStatus() {
+	state := lcServiceImpl.FSM.Current()
+	if lcServiceImpl.regService.UpdateComponentStatus(lcServiceImpl.componentID, state) {
+		lcServiceImpl.publishedState = state
+		lcServiceImpl.statusUpdatedAt = time.Now()
+	}
+}
+
+// checkTimeout moves the component to FAILED, stopping its process, when it stayed in the current state
+// longer than allowed. A FAILED component is restarted once the restart delay expired, without one it
+// stays FAILED until it is restarted through the agent api.
+func (lcServiceImpl *LifeCycleServiceImpl) checkTimeout() bool {
+	current := lcServiceImpl.FSM.Current()
+	elapsed := time.Since(lcServiceImpl.stateEnteredAt)
+	if current == "FAILED" {
+		delay, ok := lcServiceImpl.settings.GetRestartDelay()
+		if !ok || elapsed <= delay {
+			return false
+		}
+		return lcServiceImpl.recover(elapsed)
+	}
+
+	timeout, ok := lcServiceImpl.settings.GetStateTimeout(current)
+	if !ok || elapsed <= timeout || !lcServiceImpl.FSM.Can("fail") {
+		return false
+	}
+
+	lcServiceImpl.log.Errorf("component exceeded the %s timeout in state %s (elapsed %s)", timeout, current, elapsed)
+	lcServiceImpl.resetReload()
+	if !lcServiceImpl.mComponent.Stop() {
+		lcServiceImpl.log.Errorf("unable to stop %s", lcServiceImpl.mcConfig.Name)
+	}
+	err := lcServiceImpl.fire("fail", TriggerHeartbeat, fmt.Errorf("exceeded the %s timeout in state %s", timeout, current))
+	if err != nil {
+		lcServiceImpl.log.Errorln(err)
+		return false
+	}
+	return true
+}
+
+// recover restarts a FAILED component, its lifecycle starts over keeping the registration
+func (lcServiceImpl *LifeCycleServiceImpl) recover(elapsed time.Duration) bool {
+	lcServiceImpl.log.Infof("restarting %s after %s in state FAILED", lcServiceImpl.mcConfig.Name, elapsed)
+	// a component failed by a rolled back reload may still be running
+	if !lcServiceImpl.mComponent.Stop() {
+		lcServiceImpl.log.Errorf("unable to stop %s", lcServiceImpl.mcConfig.Name)
+		return false
+	}
+	err := lcServiceImpl.fire(ActionRestart, TriggerHeartbeat, nil)
+	if err != nil {
+		lcServiceImpl.log.Errorln(err)
+		return false
+	}
+	return true
 }
 
 func (lcServiceImpl *LifeCycleServiceImpl) switchState() bool {
@@ -89,6 +419,10 @@ func (lcServiceImpl *LifeCycleServiceImpl) switchState() bool {
 		return true
 	}
 
+	if lcServiceImpl.FSM.Is("RELOAD") && lcServiceImpl.reload() {
+		return true
+	}
+
 	return false
 }
 
@@ -101,33 +435,49 @@ func (lcServiceImpl *LifeCycleServiceImpl) initialize() bool {
 		return false
 	}
 
-	// register
-	if lcServiceImpl.regService.Register() {
-		log.Infoln("Registration SUCCESS")
-
-		// update state
-		err := lcServiceImpl.FSM.Event("initialize")
-		if err != nil {
-			log.Errorln(err)
+	// register, unless the component kept its registration across a configuration reload or agent restart
+	if lcServiceImpl.componentID == "" {
+		componentID, ok := lcServiceImpl.regService.RegisterComponent(lcServiceImpl.mcConfig)
+		if !ok {
+			lcServiceImpl.log.Infof("Registration of [%s] FAIL", lcServiceImpl.mcConfig.Name)
 			return false
 		}
-		return true
+		lcServiceImpl.log.Infof("Registration of [%s] SUCCESS", lcServiceImpl.mcConfig.Name)
+		lcServiceImpl.componentID = componentID
 	}
-	log.Infoln("Registration FAIL")
 
-	return false
+	// hand the registry ids to the component
+	ci := lcServiceImpl.regService.ContainerInstance()
+	lcServiceImpl.mcConfig.ContainerInstance = ci
+	lcServiceImpl.mComponent.SetContainerInstance(ci)
+
+	// update state
+	err := lcServiceImpl.fire("initialize", TriggerHeartbeat, nil)
+	if err != nil {
+		lcServiceImpl.log.Errorln(err)
+		return false
+	}
+	return true
 }
 
 func (lcServiceImpl *LifeCycleServiceImpl) resolveDependencies() bool {
+	// wait for the components this one depends on
+	for _, dep := range lcServiceImpl.mcConfig.DependsOn {
+		if state := lcServiceImpl.stateOf(dep); state != "ACTIVE" {
+			lcServiceImpl.log.Debugf("%s is waiting for %s (%s)", lcServiceImpl.mcConfig.Name, dep, state)
+			return false
+		}
+	}
+
 	// resolve
 	if !lcServiceImpl.mComponent.BuildConfiguration() {
 		return false
 	}
 
 	// update state
-	err := lcServiceImpl.FSM.Event("resolveDependencies")
+	err := lcServiceImpl.fire("resolveDependencies", TriggerHeartbeat, nil)
 	if err != nil {
-		log.Errorln(err)
+		lcServiceImpl.log.Errorln(err)
 		return false
 	}
 	return true
@@ -139,9 +489,9 @@ func (lcServiceImpl *LifeCycleServiceImpl) activate() bool {
 		return false
 	}
 	// update state
-	err := lcServiceImpl.FSM.Event("activate")
+	err := lcServiceImpl.fire("activate", TriggerHeartbeat, nil)
 	if err != nil {
-		log.Errorln(err)
+		lcServiceImpl.log.Errorln(err)
 		return false
 	}
 
@@ -154,35 +504,154 @@ func (lcServiceImpl *LifeCycleServiceImpl) standby() bool {
 		return false
 	}
 	// update state
-	err := lcServiceImpl.FSM.Event("standby")
+	err := lcServiceImpl.fire("standby", TriggerHeartbeat, nil)
 	if err != nil {
-		log.Errorln(err)
+		lcServiceImpl.log.Errorln(err)
 		return false
 	}
 	return true
 }
 
 func (lcServiceImpl *LifeCycleServiceImpl) monitor() bool {
-	// monitor
+	// the process exited, launch it again
+	if !lcServiceImpl.mComponent.Running() {
+		lcServiceImpl.log.Errorf("[monitor] %s is not running, relaunching", lcServiceImpl.mcConfig.Name)
+		lcServiceImpl.setState("RESOLVED", "relaunch", TriggerProbe, errors.New("component process exited"))
+		return true
+	}
+
+	// the process runs but fails its health probe, it is restarted after too many consecutive failures
 	if !lcServiceImpl.mComponent.WatchComponent() {
-		return false
+		lcServiceImpl.probeFailures++
+		threshold := lcServiceImpl.mcConfig.GetHealthFailureThreshold()
+		if lcServiceImpl.probeFailures < threshold {
+			lcServiceImpl.log.Warnf("[monitor] health probe of %s failed (%d of %d)", lcServiceImpl.mcConfig.Name, lcServiceImpl.probeFailures, threshold)
+			return false
+		}
+		lcServiceImpl.log.Errorf("[monitor] %s failed %d consecutive health probes, restarting", lcServiceImpl.mcConfig.Name, threshold)
+		if !lcServiceImpl.mComponent.Stop() {
+			lcServiceImpl.log.Errorf("[monitor] unable to stop %s", lcServiceImpl.mcConfig.Name)
+			return false
+		}
+		err := lcServiceImpl.fire(ActionRestart, TriggerProbe, fmt.Errorf("failed %d consecutive health probes", threshold))
+		if err != nil {
+			lcServiceImpl.log.Errorln(err)
+			return false
+		}
+		return true
+	}
+	lcServiceImpl.probeFailures = 0
+
+	// configuration changed
+	if lcServiceImpl.mComponent.NeedsReload() {
+		lcServiceImpl.reloadTrigger = TriggerConfig
+		err := lcServiceImpl.fire("reload", TriggerConfig, nil)
+		if err != nil {
+			lcServiceImpl.log.Errorln(err)
+			return false
+		}
+		return true
 	}
 	// update state
-	err := lcServiceImpl.FSM.Event("monitor")
+	err := lcServiceImpl.fire("monitor", TriggerHeartbeat, nil)
 	if err != nil && err.Error() != "no transition" {
-		log.Errorln(err)
-		lcServiceImpl.FSM.SetState("RESOLVED")
+		lcServiceImpl.log.Errorln(err)
+		lcServiceImpl.setState("RESOLVED", "monitor", TriggerHeartbeat, err)
 		return false
 	}
-	log.Infof("[monitor] Current state: %s", lcServiceImpl.FSM.Current())
+	lcServiceImpl.log.Infof("[monitor] Current state: %s", lcServiceImpl.FSM.Current())
 	return true
 }
 
+func (lcServiceImpl *LifeCycleServiceImpl) reload() bool {
+	if !lcServiceImpl.reloadApplied {
+		// keep the current configuration to roll back to
+		lcServiceImpl.configBackup = nil
+		if configFile := lcServiceImpl.mcConfig.ConfigFile; configFile != "" {
+			backup, err := ioutil.ReadFile(configFile)
+			if err == nil {
+				lcServiceImpl.configBackup = backup
+			}
+		}
+
+		// rebuild configuration and apply it to the running component, an invalid
+		// configuration is never applied and the component keeps running as is
+		if !lcServiceImpl.mComponent.BuildConfiguration() {
+			lcServiceImpl.log.Errorf("[reload] unable to build configuration of %s, keeping the running configuration", lcServiceImpl.mcConfig.Name)
+			err := lcServiceImpl.fire("reloaded", lcServiceImpl.reloadTrigger, errors.New("unable to build configuration, kept the running configuration"))
+			if err != nil {
+				lcServiceImpl.log.Errorln(err)
+				return false
+			}
+			return true
+		}
+		lcServiceImpl.reloadApplied = true
+		lcServiceImpl.reloadDeadline = time.Now().Add(lcServiceImpl.mcConfig.Reload.GetVerifyTimeout())
+		if !lcServiceImpl.mComponent.Reload() {
+			lcServiceImpl.log.Errorf("[reload] unable to reload %s", lcServiceImpl.mcConfig.Name)
+			return lcServiceImpl.rollback(lcServiceImpl.reloadTrigger, errors.New("unable to reload"))
+		}
+		return false
+	}
+
+	// verify the reloaded component passes its probes
+	if lcServiceImpl.mComponent.Running() && lcServiceImpl.mComponent.WatchComponent() {
+		lcServiceImpl.log.Infof("[reload] %s is healthy after reload", lcServiceImpl.mcConfig.Name)
+		lcServiceImpl.resetReload()
+		// update state
+		err := lcServiceImpl.fire("reloaded", lcServiceImpl.reloadTrigger, nil)
+		if err != nil {
+			lcServiceImpl.log.Errorln(err)
+			return false
+		}
+		return true
+	}
+
+	if time.Now().Before(lcServiceImpl.reloadDeadline) {
+		return false
+	}
+	lcServiceImpl.log.Errorf("[reload] %s did not become healthy within %s", lcServiceImpl.mcConfig.Name, lcServiceImpl.mcConfig.Reload.GetVerifyTimeout())
+	return lcServiceImpl.rollback(TriggerProbe, fmt.Errorf("not healthy within %s after reload", lcServiceImpl.mcConfig.Reload.GetVerifyTimeout()))
+}
+
+// rollback restores the configuration file from before the reload and reloads it,
+// the component fails when it is still unhealthy with the previous configuration
+func (lcServiceImpl *LifeCycleServiceImpl) rollback(trigger string, cause error) bool {
+	if lcServiceImpl.rolledBack || lcServiceImpl.configBackup == nil {
+		lcServiceImpl.resetReload()
+		err := lcServiceImpl.fire("fail", trigger, cause)
+		if err != nil {
+			lcServiceImpl.log.Errorln(err)
+			return false
+		}
+		return true
+	}
+
+	lcServiceImpl.log.Infof("[reload] rolling back %s to the previous configuration", lcServiceImpl.mcConfig.Name)
+	lcServiceImpl.rolledBack = true
+	err := util.WriteFileAtomic(lcServiceImpl.mcConfig.ConfigFile, lcServiceImpl.configBackup, 0644)
+	if err != nil {
+		lcServiceImpl.log.Errorln(err)
+		return false
+	}
+	lcServiceImpl.reloadDeadline = time.Now().Add(lcServiceImpl.mcConfig.Reload.GetVerifyTimeout())
+	if !lcServiceImpl.mComponent.Reload() {
+		return lcServiceImpl.rollback(trigger, errors.New("unable to reload the previous configuration"))
+	}
+	return false
+}
+
+func (lcServiceImpl *LifeCycleServiceImpl) resetReload() {
+	lcServiceImpl.reloadApplied = false
+	lcServiceImpl.rolledBack = false
+	lcServiceImpl.configBackup = nil
+}
+
 func (lcServiceImpl *LifeCycleServiceImpl) deavtivate() bool {
 	// deavtivate
-	err := lcServiceImpl.FSM.Event("deavtivate")
+	err := lcServiceImpl.fire("deavtivate", TriggerHeartbeat, nil)
 	if err != nil {
-		log.Errorln(err)
+		lcServiceImpl.log.Errorln(err)
 		return false
 	}
 	return true
@@ -0,0 +1,345 @@
+package lifecycleservice
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/rameshpolishetti/mlca/internal/core/common/statefile"
+	"github.com/rameshpolishetti/mlca/internal/core/component"
+	"github.com/rameshpolishetti/mlca/internal/core/service"
+)
+
+// fakeComponent component scripted by the tests, it counts the calls made by the lifecycle
+type fakeComponent struct {
+	mc config.ManagedComponent
+
+	running     bool
+	healthy     bool
+	needsReload bool
+	// BuildConfiguration fails when set, it writes the build number to the configuration file otherwise
+	invalid bool
+	// health of the component after each Reload, healthy when not scripted
+	healthAfterReload []bool
+	// Adopt succeeds when set, the process of the previous agent run is still running
+	alive bool
+
+	builds, launches, reloads, stops int
+}
+
+func (fc *fakeComponent) Bootup() bool { return true }
+
+func (fc *fakeComponent) BuildConfiguration() bool {
+	if fc.invalid {
+		return false
+	}
+	fc.builds++
+	fc.needsReload = false
+	if fc.mc.ConfigFile != "" {
+		ioutil.WriteFile(fc.mc.ConfigFile, []byte(fmt.Sprintf("build %d", fc.builds)), 0644)
+	}
+	return true
+}
+
+func (fc *fakeComponent) LaunchComponent() bool { return true }
+
+// PrepareForActive starts the process unless it is running, like the components do
+func (fc *fakeComponent) PrepareForActive() bool {
+	if !fc.running {
+		fc.launches++
+		fc.running = true
+		fc.healthy = true
+	}
+	return true
+}
+
+func (fc *fakeComponent) WatchComponent() bool { return fc.healthy }
+func (fc *fakeComponent) Running() bool        { return fc.running }
+func (fc *fakeComponent) NeedsReload() bool    { return fc.needsReload }
+
+func (fc *fakeComponent) Reload() bool {
+	fc.reloads++
+	fc.healthy = len(fc.healthAfterReload) < fc.reloads || fc.healthAfterReload[fc.reloads-1]
+	return true
+}
+
+func (fc *fakeComponent) Stop() bool {
+	fc.stops++
+	fc.running = false
+	return true
+}
+
+func (fc *fakeComponent) SetContainerInstance(ci config.ContainerInstance) {}
+
+func (fc *fakeComponent) Pid() int {
+	if fc.running {
+		return 4242
+	}
+	return 0
+}
+
+func (fc *fakeComponent) Adopt(pid int) bool {
+	if fc.alive {
+		fc.running = true
+		fc.healthy = true
+	}
+	return fc.alive
+}
+
+// testRegistry registry accepting every registration, it records the requests it served
+type testRegistry struct {
+	*httptest.Server
+	mutex      sync.Mutex
+	requests   []string
+	components int
+}
+
+func newTestRegistry() *testRegistry {
+	tr := &testRegistry{}
+	tr.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tr.mutex.Lock()
+		defer tr.mutex.Unlock()
+		path := strings.TrimPrefix(r.URL.Path, "/registry/rest/v1")
+		tr.requests = append(tr.requests, r.Method+" "+path)
+		switch {
+		case path == "/status":
+			fmt.Fprint(w, `{"status":"REGISTRY_READY"}`)
+		case r.Method == http.MethodPost && strings.HasSuffix(path, "/components"):
+			tr.components++
+			fmt.Fprintf(w, `{"componentId":"component-%d","status":"registered"}`, tr.components)
+		case r.Method == http.MethodPost:
+			fmt.Fprint(w, `{"tmgcId":"tm-1","zoneId":"zone-1","clusterId":"cluster-1","status":"registered"}`)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	return tr
+}
+
+// served returns the requests served with the method, e.g. "DELETE"
+func (tr *testRegistry) served(method string) []string {
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+	var requests []string
+	for _, r := range tr.requests {
+		if strings.HasPrefix(r, method+" ") {
+			requests = append(requests, strings.TrimPrefix(r, method+" "))
+		}
+	}
+	return requests
+}
+
+func (tr *testRegistry) container() config.ContainerDaemon {
+	return config.ContainerDaemon{
+		Name:          "mashling",
+		ComponentType: "trafficmanagers",
+		Cluster:       "cluster",
+		Zone:          "zone",
+		Inboxes:       map[string]string{"registry": tr.URL},
+	}
+}
+
+// newTestService creates the lifecycle service of a fake component registered with the test registry
+func newTestService(registry *testRegistry, mc config.ManagedComponent) (*LifeCycleServiceImpl, *fakeComponent) {
+	fc := &fakeComponent{mc: mc}
+	factory := func(mc config.ManagedComponent, s component.Services) component.Component { return fc }
+	rService := service.NewRegistryProxyService(registry.container())
+	stateOf := func(string) string { return "ACTIVE" }
+	lcs := NewLifeCycleService(mc, factory, component.Services{}, rService, &config.LifecycleSettings{}, NewHistory(), stateOf)
+	return lcs.(*LifeCycleServiceImpl), fc
+}
+
+// heartbeats runs the lifecycle until the component is in state, failing after n heartbeats
+func heartbeats(t *testing.T, lcs LifeCycleService, state string, n int) {
+	t.Helper()
+	for i := 0; i < n && lcs.State() != state; i++ {
+		lcs.CheckState()
+	}
+	if lcs.State() != state {
+		t.Fatalf("state = %s after %d heartbeats, want %s", lcs.State(), n, state)
+	}
+}
+
+// lastTransition returns the last transition of a history as "src -> dst (trigger) error"
+func lastTransition(history []Transition) string {
+	if len(history) == 0 {
+		return ""
+	}
+	t := history[len(history)-1]
+	return strings.TrimSpace(fmt.Sprintf("%s -> %s (%s) %s", t.Src, t.Dst, t.Trigger, t.Error))
+}
+
+func TestReloadVerifiesHealth(t *testing.T) {
+	registry := newTestRegistry()
+	defer registry.Close()
+
+	tests := []struct {
+		name              string
+		invalid           bool
+		healthAfterReload []bool
+		wantState         string
+		wantReloads       int
+		wantConfig        string
+		wantTransition    string
+	}{
+		{
+			name:           "healthy with the new configuration",
+			wantState:      "ACTIVE",
+			wantReloads:    1,
+			wantConfig:     "build 2",
+			wantTransition: "RELOAD -> ACTIVE (config)",
+		},
+		{
+			name:              "rolled back to the previous configuration",
+			healthAfterReload: []bool{false, true},
+			wantState:         "ACTIVE",
+			wantReloads:       2,
+			wantConfig:        "build 1",
+			wantTransition:    "RELOAD -> ACTIVE (config)",
+		},
+		{
+			name:              "unhealthy with the previous configuration",
+			healthAfterReload: []bool{false, false},
+			wantState:         "FAILED",
+			wantReloads:       2,
+			wantConfig:        "build 1",
+			wantTransition:    "RELOAD -> FAILED (probe) not healthy within 10ms after reload",
+		},
+		{
+			name:           "invalid configuration",
+			invalid:        true,
+			wantState:      "ACTIVE",
+			wantConfig:     "build 1",
+			wantTransition: "RELOAD -> ACTIVE (config) unable to build configuration, kept the running configuration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "lifecycle")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			lcs, fc := newTestService(registry, config.ManagedComponent{
+				Name:       "TMG-Microgateway",
+				ConfigFile: filepath.Join(dir, "mashling.json"),
+				Reload:     config.ReloadSettings{VerifyTimeout: 10},
+			})
+			heartbeats(t, lcs, "ACTIVE", 5)
+
+			fc.needsReload = true
+			fc.invalid = tt.invalid
+			fc.healthAfterReload = tt.healthAfterReload
+			heartbeats(t, lcs, "RELOAD", 1)
+			// the reloaded component is verified until it is healthy or the verify timeout expired
+			for i := 0; i < 10 && lcs.State() == "RELOAD"; i++ {
+				lcs.CheckState()
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			if lcs.State() != tt.wantState {
+				t.Errorf("state = %s, want %s", lcs.State(), tt.wantState)
+			}
+			if fc.reloads != tt.wantReloads {
+				t.Errorf("reloads = %d, want %d", fc.reloads, tt.wantReloads)
+			}
+			if b, _ := ioutil.ReadFile(lcs.Config().ConfigFile); string(b) != tt.wantConfig {
+				t.Errorf("configuration = %q, want %q", b, tt.wantConfig)
+			}
+			if got := lastTransition(lcs.History()); got != tt.wantTransition {
+				t.Errorf("last transition = %q, want %q", got, tt.wantTransition)
+			}
+			if fc.launches != 1 {
+				t.Errorf("launched %d times, a reload must not launch the component again", fc.launches)
+			}
+		})
+	}
+}
+
+func TestRestartAfterFailedHealthProbes(t *testing.T) {
+	registry := newTestRegistry()
+	defer registry.Close()
+	lcs, fc := newTestService(registry, config.ManagedComponent{Name: "TMG-LFA", HealthFailureThreshold: 2})
+	heartbeats(t, lcs, "ACTIVE", 5)
+
+	fc.healthy = false
+	lcs.CheckState()
+	if lcs.State() != "ACTIVE" || fc.stops != 0 {
+		t.Fatalf("state = %s with %d stops after one failed probe, want ACTIVE", lcs.State(), fc.stops)
+	}
+	lcs.CheckState()
+	if lcs.State() != "UNKNOWN" || fc.stops != 1 {
+		t.Fatalf("state = %s with %d stops after two failed probes, want UNKNOWN after a stop", lcs.State(), fc.stops)
+	}
+	if got, want := lastTransition(lcs.History()), "ACTIVE -> UNKNOWN (probe) failed 2 consecutive health probes"; got != want {
+		t.Errorf("last transition = %q, want %q", got, want)
+	}
+
+	// the lifecycle starts over keeping the registration
+	heartbeats(t, lcs, "ACTIVE", 5)
+	if components := registry.served("POST"); len(components) != 2 {
+		t.Errorf("registry served %v, want the container and the component registered once", components)
+	}
+}
+
+func TestResume(t *testing.T) {
+	registry := newTestRegistry()
+	defer registry.Close()
+
+	tests := []struct {
+		state string
+		alive bool
+		// state after the first heartbeat
+		wantState string
+		wantStops int
+	}{
+		{state: "ACTIVE", alive: true, wantState: "ACTIVE"},
+		{state: "STANDBY", alive: true, wantState: "ACTIVE"},
+		{state: "RELOAD", alive: true, wantState: "ACTIVE"},
+		{state: "ACTIVE", wantState: "UNSATISFIED"},
+		{state: "FAILED", alive: true, wantState: "UNSATISFIED", wantStops: 1},
+		{state: "UNSATISFIED", alive: true, wantState: "UNSATISFIED", wantStops: 1},
+		{state: "DISABLED", alive: true, wantState: "DISABLED", wantStops: 1},
+	}
+
+	for _, tt := range tests {
+		name := tt.state
+		if !tt.alive {
+			name += " process gone"
+		}
+		t.Run(name, func(t *testing.T) {
+			lcs, fc := newTestService(registry, config.ManagedComponent{Name: "TMG-LFA"})
+			fc.alive = tt.alive
+			lcs.Resume(statefile.Component{Name: "TMG-LFA", ComponentID: "component-7", Pid: 4242, State: tt.state})
+			lcs.CheckState()
+
+			if lcs.State() != tt.wantState {
+				t.Errorf("state = %s, want %s", lcs.State(), tt.wantState)
+			}
+			if fc.stops != tt.wantStops {
+				t.Errorf("left over process stopped %d times, want %d", fc.stops, tt.wantStops)
+			}
+			if fc.launches != 0 {
+				t.Errorf("launched %d times on the first heartbeat", fc.launches)
+			}
+			if lcs.ComponentID() != "component-7" {
+				t.Errorf("component id = %q, want the registration of the previous run", lcs.ComponentID())
+			}
+		})
+	}
+
+	// components resumed or started over keep their registration
+	if registered := registry.served("POST"); len(registered) != 0 {
+		t.Errorf("registry served %v, want no registration", registered)
+	}
+}
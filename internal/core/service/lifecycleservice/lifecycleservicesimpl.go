@@ -1,10 +1,19 @@
 package lifecycleservice
 
 import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
 	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/rameshpolishetti/mlca/internal/core/common/statefile"
 	"github.com/rameshpolishetti/mlca/internal/core/component"
-	"github.com/rameshpolishetti/mlca/internal/core/component/lfa"
-	"github.com/rameshpolishetti/mlca/internal/core/component/mgw"
+	// register the component factories
+	_ "github.com/rameshpolishetti/mlca/internal/core/component/lfa"
+	_ "github.com/rameshpolishetti/mlca/internal/core/component/mgw"
 	"github.com/rameshpolishetti/mlca/internal/core/service"
 	"github.com/rameshpolishetti/mlca/logger"
 )
@@ -14,43 +23,85 @@ var log = logger.GetLogger("lifecycle-service")
 // LifeCycleServices
 type LifeCycleServices interface {
 	CheckState() bool
+	Status() string
+	Policy() string
+	ComponentStatuses() []ComponentStatus
+	Components() []LifeCycleService
+	ApplyConfiguration(cDaemon config.ContainerDaemon) error
+	Resume(s *statefile.State)
+	History(name string) ([]Transition, bool)
+	Control(name, action string) (ComponentStatus, error)
 }
 
 // LifeCycleServicesImpl LifeCycleServiceImpl
 type LifeCycleServicesImpl struct {
-	containerDaemon config.ContainerDaemon
-	managedServices map[string]LifeCycleService
+	// guards managedServices and containerDaemon, which are replaced on configuration reload
+	mutex           sync.RWMutex
+	containerDaemon *config.ContainerDaemon
+	managedServices []LifeCycleService
 	regService      *service.RegistryProxy
+	cServices       component.Services
+	// state transitions of each component, by name
+	histories map[string]*History
+
+	// last container status published to registry
+	publishedStatus string
+	statusUpdatedAt time.Time
+
+	// state of a previous agent run, resumed once the container registration is settled
+	pendingResume *statefile.State
 }
 
 // NewLifeCycleServices creates new LifeCycleServiceImpl
-func NewLifeCycleServices(cDaemon config.ContainerDaemon) LifeCycleServices {
-
-	// load managed services map
-	mServices := make(map[string]LifeCycleService)
-	rService := service.NewRegistryProxyService(cDaemon)
-
-	for _, c := range cDaemon.Components {
-		var mc component.Component
-		if c.Type == "Microgateway" {
-			mc = mgw.NewMicrogatewayComponent(c)
-		} else if c.Type == "Log" {
-			mc = lfa.NewLFAComponent(c)
-		} else {
-			log.Panicf("managed component of type %s not found", c.Type)
-		}
-		mServices[c.Name] = NewLifeCycleService(mc, rService)
-	}
-
+func NewLifeCycleServices(cDaemon config.ContainerDaemon, rService *service.RegistryProxy, mService *service.ManagerProxy) LifeCycleServices {
 	lcServicesImpl := &LifeCycleServicesImpl{
-		containerDaemon: cDaemon,
-		managedServices: mServices,
+		containerDaemon: &cDaemon,
 		regService:      rService,
+		histories:       make(map[string]*History),
+	}
+	lcServicesImpl.cServices = component.Services{
+		Registry:  rService,
+		Manager:   mService,
+		Container: lcServicesImpl.containerDaemon,
+	}
+
+	// load managed services, in dependency order
+	components, err := config.DependencyOrder(cDaemon.Components)
+	if err != nil {
+		log.Panicln(err)
+	}
+	lcServicesImpl.managedServices = make([]LifeCycleService, 0, len(components))
+	for _, c := range components {
+		lcServicesImpl.managedServices = append(lcServicesImpl.managedServices, lcServicesImpl.newLifeCycleService(c))
 	}
 
 	return lcServicesImpl
 }
 
+func (lcServicesImpl *LifeCycleServicesImpl) newLifeCycleService(c config.ManagedComponent) LifeCycleService {
+	factory, ok := component.GetFactory(c.Factory)
+	if !ok {
+		log.Panicf("managed component factory %s not found", c.Factory)
+	}
+	c.ContainerInstance = lcServicesImpl.regService.ContainerInstance()
+	history, ok := lcServicesImpl.histories[c.Name]
+	if !ok {
+		history = NewHistory()
+		lcServicesImpl.histories[c.Name] = history
+	}
+	return NewLifeCycleService(c, factory, lcServicesImpl.cServices, lcServicesImpl.regService, &lcServicesImpl.containerDaemon.Lifecycle, history, lcServicesImpl.stateOf)
+}
+
+// stateOf returns current state of a managed component, empty if there is no such component
+func (lcServicesImpl *LifeCycleServicesImpl) stateOf(name string) string {
+	for _, mService := range lcServicesImpl.managedServices {
+		if mService.Name() == name {
+			return mService.State()
+		}
+	}
+	return ""
+}
+
 // CheckState check managed component state
 func (lcServicesImpl *LifeCycleServicesImpl) CheckState() bool {
 	result := false
@@ -58,10 +109,268 @@ func (lcServicesImpl *LifeCycleServicesImpl) CheckState() bool {
 		log.Info("Registry is not ready")
 		return result
 	}
+
+	// register the container once, components are registered as its sub-resources
+	if !lcServicesImpl.regService.Register() {
+		log.Info("Container registration FAIL")
+		return result
+	}
+
+	if lcServicesImpl.pendingResume != nil {
+		lcServicesImpl.resume(lcServicesImpl.pendingResume)
+		lcServicesImpl.pendingResume = nil
+	}
+
 	for _, mService := range lcServicesImpl.managedServices {
 		if mService.CheckState() {
 			result = true
 		}
 	}
+
+	lcServicesImpl.updateStatus()
 	return result
 }
+
+// Resume resumes the components persisted by a previous agent run on the next CheckState,
+// after the container registration was reused or replaced
+func (lcServicesImpl *LifeCycleServicesImpl) Resume(s *statefile.State) {
+	lcServicesImpl.pendingResume = s
+}
+
+func (lcServicesImpl *LifeCycleServicesImpl) resume(s *statefile.State) {
+	restored := lcServicesImpl.regService.RegistrationRestored()
+	for _, mService := range lcServicesImpl.managedServices {
+		c, ok := s.Component(mService.Name())
+		if !ok {
+			continue
+		}
+		// component registrations belong to the container registration
+		if !restored {
+			c.ComponentID = ""
+		}
+		mService.Resume(c)
+	}
+}
+
+// Status returns aggregate container status derived from managed component states by the configured policy
+func (lcServicesImpl *LifeCycleServicesImpl) Status() string {
+	lcServicesImpl.mutex.RLock()
+	defer lcServicesImpl.mutex.RUnlock()
+	return lcServicesImpl.status()
+}
+
+func (lcServicesImpl *LifeCycleServicesImpl) status() string {
+	return AggregateStatus(lcServicesImpl.containerDaemon.StatusPolicy, lcServicesImpl.componentStatuses())
+}
+
+// Policy returns the status aggregation policy in effect
+func (lcServicesImpl *LifeCycleServicesImpl) Policy() string {
+	lcServicesImpl.mutex.RLock()
+	defer lcServicesImpl.mutex.RUnlock()
+	if lcServicesImpl.containerDaemon.StatusPolicy.Type == "" {
+		return PolicyWorstOf
+	}
+	return lcServicesImpl.containerDaemon.StatusPolicy.Type
+}
+
+// ComponentStatuses returns status of each managed component
+func (lcServicesImpl *LifeCycleServicesImpl) ComponentStatuses() []ComponentStatus {
+	lcServicesImpl.mutex.RLock()
+	defer lcServicesImpl.mutex.RUnlock()
+	return lcServicesImpl.componentStatuses()
+}
+
+func (lcServicesImpl *LifeCycleServicesImpl) componentStatuses() []ComponentStatus {
+	statuses := make([]ComponentStatus, 0, len(lcServicesImpl.managedServices))
+	for _, mService := range lcServicesImpl.managedServices {
+		statuses = append(statuses, mService.Status())
+	}
+	return statuses
+}
+
+// Components returns lifecycle services of managed components
+func (lcServicesImpl *LifeCycleServicesImpl) Components() []LifeCycleService {
+	lcServicesImpl.mutex.RLock()
+	defer lcServicesImpl.mutex.RUnlock()
+	return append([]LifeCycleService(nil), lcServicesImpl.managedServices...)
+}
+
+// History returns the state transitions of a managed component, false if there is no such component
+func (lcServicesImpl *LifeCycleServicesImpl) History(name string) ([]Transition, bool) {
+	lcServicesImpl.mutex.RLock()
+	defer lcServicesImpl.mutex.RUnlock()
+	for _, mService := range lcServicesImpl.managedServices {
+		if mService.Name() == name {
+			return mService.History(), true
+		}
+	}
+	return nil, false
+}
+
+// Control applies an action requested through the agent api to a managed component and returns its
+// status, ErrUnknownComponent is returned when there is no such component
+func (lcServicesImpl *LifeCycleServicesImpl) Control(name, action string) (ComponentStatus, error) {
+	lcServicesImpl.mutex.RLock()
+	defer lcServicesImpl.mutex.RUnlock()
+	for _, mService := range lcServicesImpl.managedServices {
+		if mService.Name() == name {
+			err := mService.Control(action)
+			return mService.Status(), err
+		}
+	}
+	return ComponentStatus{}, ErrUnknownComponent
+}
+
+// ApplyConfiguration applies a reloaded container configuration incrementally: new components are
+// added, removed ones are stopped and only components whose spec changed are restarted. Changes
+// that require an agent restart, e.g. to the container identity or the registry url, are rejected
+// as a whole.
+func (lcServicesImpl *LifeCycleServicesImpl) ApplyConfiguration(cDaemon config.ContainerDaemon) error {
+	lcServicesImpl.mutex.Lock()
+	defer lcServicesImpl.mutex.Unlock()
+
+	current := lcServicesImpl.containerDaemon
+	err := checkRestartRequired(*current, cDaemon)
+	if err != nil {
+		return err
+	}
+
+	components, err := config.DependencyOrder(cDaemon.Components)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]LifeCycleService)
+	for _, mService := range lcServicesImpl.managedServices {
+		existing[mService.Name()] = mService
+	}
+
+	mServices := make([]LifeCycleService, 0, len(cDaemon.Components))
+	retained := make(map[string]bool)
+	for _, c := range components {
+		mService, ok := existing[c.Name]
+		if !ok {
+			log.Infof("adding component [%s]", c.Name)
+			mServices = append(mServices, lcServicesImpl.newLifeCycleService(c))
+			continue
+		}
+
+		retained[c.Name] = true
+		if sameSpec(mService.Config(), c) {
+			mServices = append(mServices, mService)
+			continue
+		}
+
+		// restart with the new spec, keeping its registration
+		log.Infof("component [%s] changed, restarting it", c.Name)
+		mService.Stop(false)
+		lcServicesImpl.recordRestart(mService, cDaemon.Lifecycle)
+		replacement := lcServicesImpl.newLifeCycleService(c)
+		replacement.SetComponentID(mService.ComponentID())
+		// a disabled component stays disabled with its new spec
+		if mService.State() == "DISABLED" {
+			replacement.Control(ActionDisable)
+		}
+		mServices = append(mServices, replacement)
+	}
+
+	for name, mService := range existing {
+		if !retained[name] {
+			log.Infof("removing component [%s]", name)
+			mService.Stop(true)
+			delete(lcServicesImpl.histories, name)
+		}
+	}
+
+	// components share the container configuration, update it in place
+	cDaemon.IP = current.IP
+	cDaemon.Node = current.Node
+	*current = cDaemon
+	lcServicesImpl.managedServices = mServices
+	return nil
+}
+
+// recordRestart records the restart of a component whose spec changed, its replacement starts over from UNKNOWN
+func (lcServicesImpl *LifeCycleServicesImpl) recordRestart(mService LifeCycleService, settings config.LifecycleSettings) {
+	t := Transition{
+		Timestamp: time.Now(),
+		Component: mService.Name(),
+		Event:     "restart",
+		Src:       mService.State(),
+		Dst:       "UNKNOWN",
+		Trigger:   TriggerConfig,
+	}
+	err := lcServicesImpl.histories[mService.Name()].Record(t, settings)
+	if err != nil {
+		log.Errorf("unable to write audit file: %s", err)
+	}
+}
+
+// sameSpec compares component configurations, ignoring the container identity filled in at runtime
+func sameSpec(a, b config.ManagedComponent) bool {
+	a.ContainerInstance = config.ContainerInstance{}
+	b.ContainerInstance = config.ContainerInstance{}
+	return reflect.DeepEqual(a, b)
+}
+
+// checkRestartRequired rejects changes to the fields identifying the container in the registry and to
+// the fields the registry and manager proxies, the agent api and the drop-in watcher were set up with
+// when the agent started
+func checkRestartRequired(current, updated config.ContainerDaemon) error {
+	immutable := []struct {
+		field    string
+		from, to string
+	}{
+		{"name", current.Name, updated.Name},
+		{"componentType", current.ComponentType, updated.ComponentType},
+		{"domain", current.Domain, updated.Domain},
+		{"cluster", current.Cluster, updated.Cluster},
+		{"zone", current.Zone, updated.Zone},
+		{"port", current.Port, updated.Port},
+		{"transportSettings.scheme", current.TransportSettings.Scheme, updated.TransportSettings.Scheme},
+		{"transportSettings.ip", current.TransportSettings.IP, updated.TransportSettings.IP},
+		{"transportSettings.port", strconv.Itoa(current.TransportSettings.Port), strconv.Itoa(updated.TransportSettings.Port)},
+		{"componentsDir", current.ComponentsDir, updated.ComponentsDir},
+	}
+	for _, name := range inboxNames(current.Inboxes, updated.Inboxes) {
+		immutable = append(immutable, struct {
+			field    string
+			from, to string
+		}{"inboxes." + name, current.Inboxes[name], updated.Inboxes[name]})
+	}
+	for _, f := range immutable {
+		if f.from != f.to {
+			return fmt.Errorf("changing %s from [%s] to [%s] requires an agent restart", f.field, f.from, f.to)
+		}
+	}
+	return nil
+}
+
+// inboxNames returns the sorted names of the inboxes of both configurations
+func inboxNames(a, b map[string]string) []string {
+	names := make([]string, 0, len(a)+len(b))
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// updateStatus publishes aggregate container status to registry when it changes or needs a refresh
+func (lcServicesImpl *LifeCycleServicesImpl) updateStatus() {
+	status := lcServicesImpl.Status()
+	refreshInterval := lcServicesImpl.containerDaemon.Lifecycle.GetStatusRefreshInterval()
+	if status == lcServicesImpl.publishedStatus && time.Since(lcServicesImpl.statusUpdatedAt) < refreshInterval {
+		return
+	}
+
+	if lcServicesImpl.regService.UpdateStatus(status) {
+		lcServicesImpl.publishedStatus = status
+		lcServicesImpl.statusUpdatedAt = time.Now()
+	}
+}
@@ -0,0 +1,165 @@
+package lifecycleservice
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	"github.com/rameshpolishetti/mlca/internal/core/component"
+	"github.com/rameshpolishetti/mlca/internal/core/service"
+)
+
+// fakes fake components created by the fake factory, by name, the latest one of each name
+var fakes = make(map[string]*fakeComponent)
+
+func init() {
+	component.RegisterFactory("fake", "Fake", func(mc config.ManagedComponent, s component.Services) component.Component {
+		fakes[mc.Name] = &fakeComponent{mc: mc}
+		return fakes[mc.Name]
+	})
+}
+
+func fakeSpec(name string, port int) config.ManagedComponent {
+	return config.ManagedComponent{Name: name, Type: "Fake", Factory: "fake", Port: port}
+}
+
+// activateAll runs the container lifecycle until every component is ACTIVE
+func activateAll(t *testing.T, lcServices LifeCycleServices) {
+	t.Helper()
+	for i := 0; i < 10; i++ {
+		lcServices.CheckState()
+		if lcServices.Status() == "ACTIVE" {
+			return
+		}
+	}
+	t.Fatalf("components did not become ACTIVE: %v", lcServices.ComponentStatuses())
+}
+
+func states(lcServices LifeCycleServices) map[string]string {
+	states := make(map[string]string)
+	for _, s := range lcServices.ComponentStatuses() {
+		states[s.Name] = s.State
+	}
+	return states
+}
+
+func TestApplyConfiguration(t *testing.T) {
+	registry := newTestRegistry()
+	defer registry.Close()
+
+	cDaemon := registry.container()
+	cDaemon.Components = []config.ManagedComponent{fakeSpec("kept", 9080), fakeSpec("changed", 9081), fakeSpec("removed", 9082), fakeSpec("disabled", 9083)}
+	lcServices := NewLifeCycleServices(cDaemon, service.NewRegistryProxyService(cDaemon), nil)
+	activateAll(t, lcServices)
+	if _, err := lcServices.Control("disabled", ActionDisable); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make(map[string]string)
+	services := make(map[string]LifeCycleService)
+	for _, mService := range lcServices.Components() {
+		ids[mService.Name()] = mService.ComponentID()
+		services[mService.Name()] = mService
+	}
+	kept, changed, removed := fakes["kept"], fakes["changed"], fakes["removed"]
+	registered := len(registry.served("POST"))
+
+	cDaemon.Components = []config.ManagedComponent{
+		fakeSpec("kept", 9080),
+		fakeSpec("changed", 9091),
+		fakeSpec("disabled", 9093),
+		fakeSpec("added", 9084),
+	}
+	if err := lcServices.ApplyConfiguration(cDaemon); err != nil {
+		t.Fatalf("ApplyConfiguration() error = %v", err)
+	}
+
+	want := map[string]string{"kept": "ACTIVE", "changed": "UNKNOWN", "disabled": "DISABLED", "added": "UNKNOWN"}
+	if got := states(lcServices); len(got) != len(want) || got["kept"] != want["kept"] || got["changed"] != want["changed"] ||
+		got["disabled"] != want["disabled"] || got["added"] != want["added"] {
+		t.Errorf("states after apply = %v, want %v", got, want)
+	}
+
+	// an unchanged component keeps running as is
+	for _, mService := range lcServices.Components() {
+		if mService.Name() == "kept" && mService != services["kept"] {
+			t.Error("the unchanged component was replaced")
+		}
+	}
+	if kept.stops != 0 {
+		t.Errorf("the unchanged component was stopped %d times", kept.stops)
+	}
+
+	// a changed component restarts with its new spec, keeping its registration
+	if changed.stops != 1 {
+		t.Errorf("the changed component was stopped %d times, want once", changed.stops)
+	}
+	if port := fakes["changed"].mc.Port; port != 9091 {
+		t.Errorf("the changed component restarted with port %d, want 9091", port)
+	}
+	history, _ := lcServices.History("changed")
+	if got := lastTransition(history); got != "ACTIVE -> UNKNOWN (config)" {
+		t.Errorf("last transition of the changed component = %q, want the restart", got)
+	}
+
+	// a removed component is stopped and deregistered, its history is dropped
+	if removed.stops != 1 {
+		t.Errorf("the removed component was stopped %d times, want once", removed.stops)
+	}
+	if deleted := registry.served("DELETE"); len(deleted) != 1 || !strings.HasSuffix(deleted[0], "/components/"+ids["removed"]) {
+		t.Errorf("registry deleted %v, want the removed component %s", deleted, ids["removed"])
+	}
+	if _, ok := lcServices.History("removed"); ok {
+		t.Error("the history of the removed component is kept")
+	}
+
+	activateAll(t, lcServices)
+	for _, mService := range lcServices.Components() {
+		if id, ok := ids[mService.Name()]; ok && mService.ComponentID() != id {
+			t.Errorf("%s registered again as %s, want %s", mService.Name(), mService.ComponentID(), id)
+		}
+	}
+	if got := len(registry.served("POST")) - registered; got != 1 {
+		t.Errorf("registry served %d registrations, want the added component only", got)
+	}
+}
+
+func TestApplyConfigurationRequiringRestart(t *testing.T) {
+	registry := newTestRegistry()
+	defer registry.Close()
+	cDaemon := registry.container()
+	cDaemon.ComponentsDir = "conf.d"
+	cDaemon.TransportSettings.Port = 21780
+	cDaemon.Components = []config.ManagedComponent{fakeSpec("kept", 9080)}
+
+	tests := []struct {
+		name    string
+		change  func(cd *config.ContainerDaemon)
+		wantErr string
+	}{
+		{"name", func(cd *config.ContainerDaemon) { cd.Name = "mashling-2" }, "changing name from [mashling] to [mashling-2]"},
+		{"agent port", func(cd *config.ContainerDaemon) { cd.TransportSettings.Port = 21781 }, "changing transportSettings.port from [21780] to [21781]"},
+		{"registry url", func(cd *config.ContainerDaemon) { cd.Inboxes = map[string]string{"registry": "http://registry:9090"} }, "changing inboxes.registry"},
+		{"manager url", func(cd *config.ContainerDaemon) {
+			cd.Inboxes = map[string]string{"registry": registry.URL, "manager": "http://manager"}
+		}, "changing inboxes.manager from [] to [http://manager]"},
+		{"components dir", func(cd *config.ContainerDaemon) { cd.ComponentsDir = "/etc/mlca.d" }, "changing componentsDir from [conf.d] to [/etc/mlca.d]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lcServices := NewLifeCycleServices(cDaemon, service.NewRegistryProxyService(cDaemon), nil)
+			updated := cDaemon
+			updated.Components = []config.ManagedComponent{fakeSpec("added", 9084)}
+			tt.change(&updated)
+
+			err := lcServices.ApplyConfiguration(updated)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.HasSuffix(err.Error(), "requires an agent restart") {
+				t.Fatalf("ApplyConfiguration() error = %v, want %q", err, tt.wantErr)
+			}
+			if got := states(lcServices); len(got) != 1 || got["kept"] == "" {
+				t.Errorf("components = %v, a rejected configuration must not be applied", got)
+			}
+		})
+	}
+}
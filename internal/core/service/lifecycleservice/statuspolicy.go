@@ -0,0 +1,114 @@
+package lifecycleservice
+
+import (
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+)
+
+const (
+	// PolicyWorstOf container status is the worst state among non-optional components, FAILED only for a critical one
+	PolicyWorstOf = "worst-of"
+	// PolicyAllRequired container is ACTIVE only when every non-optional component is ACTIVE
+	PolicyAllRequired = "all-required"
+	// PolicyQuorum container is ACTIVE when all critical components and a quorum of the others are ACTIVE
+	PolicyQuorum = "quorum"
+)
+
+// stateRanks orders component states from worst to best
+var stateRanks = map[string]int{
+	"FAILED":      0,
+	"UNKNOWN":     1,
+	"UNSATISFIED": 2,
+	"RESOLVED":    3,
+	"STANDBY":     4,
+	"RELOAD":      5,
+	"ACTIVE":      6,
+}
+
+// ComponentStatus state of a managed component as seen by the status policy
+type ComponentStatus struct {
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	Critical bool   `json:"critical"`
+	Optional bool   `json:"optional"`
+}
+
+// AggregateStatus computes the container status from managed component states.
+// Optional components, and components stopped or disabled through the agent api,
+// are ignored by every policy. Only a FAILED critical component fails the container;
+// under worst-of a FAILED non-critical component counts as UNSATISFIED, under the
+// other policies a container that is not ACTIVE is reported as UNSATISFIED.
+func AggregateStatus(policy config.StatusPolicy, statuses []ComponentStatus) string {
+	required := make([]ComponentStatus, 0, len(statuses))
+	for _, cs := range statuses {
+		if cs.Optional || cs.State == "STOPPED" || cs.State == "DISABLED" {
+			continue
+		}
+		required = append(required, cs)
+	}
+
+	switch policy.Type {
+	case PolicyAllRequired:
+		return allRequired(required)
+	case PolicyQuorum:
+		return quorum(required, policy.Quorum)
+	default:
+		return worstOf(required)
+	}
+}
+
+func worstOf(statuses []ComponentStatus) string {
+	status := "ACTIVE"
+	for _, cs := range statuses {
+		state := cs.State
+		if state == "FAILED" && !cs.Critical {
+			state = "UNSATISFIED"
+		}
+		if stateRanks[state] < stateRanks[status] {
+			status = state
+		}
+	}
+	return status
+}
+
+func allRequired(statuses []ComponentStatus) string {
+	status := "ACTIVE"
+	for _, cs := range statuses {
+		if cs.State == "ACTIVE" {
+			continue
+		}
+		if cs.Critical && cs.State == "FAILED" {
+			return "FAILED"
+		}
+		status = "UNSATISFIED"
+	}
+	return status
+}
+
+func quorum(statuses []ComponentStatus, min int) string {
+	status := "ACTIVE"
+	others, active := 0, 0
+	for _, cs := range statuses {
+		if cs.Critical {
+			if cs.State == "FAILED" {
+				return "FAILED"
+			}
+			if cs.State != "ACTIVE" {
+				status = "UNSATISFIED"
+			}
+			continue
+		}
+		others++
+		if cs.State == "ACTIVE" {
+			active++
+		}
+	}
+
+	// default to a majority of non-critical components
+	if min <= 0 {
+		min = others/2 + 1
+	}
+	if others > 0 && active < min {
+		status = "UNSATISFIED"
+	}
+	return status
+}
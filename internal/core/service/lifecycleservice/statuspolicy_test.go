@@ -0,0 +1,59 @@
+package lifecycleservice
+
+import (
+	"testing"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+)
+
+func TestAggregateStatus(t *testing.T) {
+	active := ComponentStatus{Name: "a", State: "ACTIVE"}
+	criticalActive := ComponentStatus{Name: "c", State: "ACTIVE", Critical: true}
+	criticalFailed := ComponentStatus{Name: "c", State: "FAILED", Critical: true}
+	criticalStandby := ComponentStatus{Name: "c", State: "STANDBY", Critical: true}
+	failed := ComponentStatus{Name: "f", State: "FAILED"}
+	standby := ComponentStatus{Name: "s", State: "STANDBY"}
+	unknown := ComponentStatus{Name: "u", State: "UNKNOWN"}
+	optionalFailed := ComponentStatus{Name: "o", State: "FAILED", Optional: true}
+	stopped := ComponentStatus{Name: "st", State: "STOPPED", Critical: true}
+	disabled := ComponentStatus{Name: "d", State: "DISABLED"}
+
+	tests := []struct {
+		name     string
+		policy   config.StatusPolicy
+		statuses []ComponentStatus
+		want     string
+	}{
+		{"worst-of no components", config.StatusPolicy{}, nil, "ACTIVE"},
+		{"worst-of all active", config.StatusPolicy{}, []ComponentStatus{active, criticalActive}, "ACTIVE"},
+		{"worst-of lowest state", config.StatusPolicy{}, []ComponentStatus{active, standby, unknown}, "UNKNOWN"},
+		{"worst-of critical failed", config.StatusPolicy{Type: PolicyWorstOf}, []ComponentStatus{active, criticalFailed}, "FAILED"},
+		{"worst-of non-critical failed", config.StatusPolicy{Type: PolicyWorstOf}, []ComponentStatus{active, failed}, "UNSATISFIED"},
+		{"worst-of non-critical failed below unknown", config.StatusPolicy{}, []ComponentStatus{failed, unknown}, "UNKNOWN"},
+		{"worst-of optional ignored", config.StatusPolicy{}, []ComponentStatus{active, optionalFailed}, "ACTIVE"},
+		{"worst-of stopped and disabled ignored", config.StatusPolicy{}, []ComponentStatus{active, stopped, disabled}, "ACTIVE"},
+
+		{"all-required all active", config.StatusPolicy{Type: PolicyAllRequired}, []ComponentStatus{active, criticalActive}, "ACTIVE"},
+		{"all-required one standby", config.StatusPolicy{Type: PolicyAllRequired}, []ComponentStatus{active, standby}, "UNSATISFIED"},
+		{"all-required critical failed", config.StatusPolicy{Type: PolicyAllRequired}, []ComponentStatus{standby, criticalFailed}, "FAILED"},
+		{"all-required non-critical failed", config.StatusPolicy{Type: PolicyAllRequired}, []ComponentStatus{active, failed}, "UNSATISFIED"},
+		{"all-required stopped and disabled ignored", config.StatusPolicy{Type: PolicyAllRequired}, []ComponentStatus{active, stopped, disabled}, "ACTIVE"},
+
+		{"quorum default majority met", config.StatusPolicy{Type: PolicyQuorum}, []ComponentStatus{criticalActive, active, active, standby}, "ACTIVE"},
+		{"quorum default majority missed", config.StatusPolicy{Type: PolicyQuorum}, []ComponentStatus{criticalActive, active, standby, failed}, "UNSATISFIED"},
+		{"quorum explicit met", config.StatusPolicy{Type: PolicyQuorum, Quorum: 1}, []ComponentStatus{criticalActive, active, standby, failed}, "ACTIVE"},
+		{"quorum critical not active", config.StatusPolicy{Type: PolicyQuorum, Quorum: 1}, []ComponentStatus{criticalStandby, active}, "UNSATISFIED"},
+		{"quorum critical failed", config.StatusPolicy{Type: PolicyQuorum}, []ComponentStatus{criticalFailed, active}, "FAILED"},
+		{"quorum only critical", config.StatusPolicy{Type: PolicyQuorum}, []ComponentStatus{criticalActive}, "ACTIVE"},
+		{"quorum disabled not counted", config.StatusPolicy{Type: PolicyQuorum}, []ComponentStatus{active, disabled}, "ACTIVE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AggregateStatus(tt.policy, tt.statuses)
+			if got != tt.want {
+				t.Errorf("AggregateStatus() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+	jsonclient "github.com/rameshpolishetti/mlca/internal/core/common/restclient"
+	"github.com/rameshpolishetti/mlca/internal/core/common/util"
+	"github.com/rameshpolishetti/mlca/logger"
+)
+
+var managerLog = logger.GetLogger("manager-service")
+
+// ComponentConfiguration configuration published by the cluster manager for a managed component
+type ComponentConfiguration struct {
+	Qualifier     string          `json:"qualifier"`
+	Version       string          `json:"version"`
+	ETag          string          `json:"etag"`
+	Configuration json.RawMessage `json:"configuration"`
+	FetchedTime   time.Time       `json:"fetchedTime"`
+}
+
+// ManagerProxy cluster manager
+type ManagerProxy struct {
+	cConfig    config.ContainerDaemon
+	jsonClient *jsonclient.JSONClient
+	cacheDir   string
+}
+
+// NewManagerProxyService creates new cluster manager proxy
+func NewManagerProxyService(cCfg config.ContainerDaemon) *ManagerProxy {
+	manager := cCfg.Inboxes["manager"]
+	managerContext := "/manager/rest/v1"
+	mp := &ManagerProxy{
+		cConfig:    cCfg,
+		jsonClient: jsonclient.New(manager, managerContext),
+		cacheDir:   cCfg.GetCacheDir(),
+	}
+	return mp
+}
+
+// Enabled return whether a manager inbox is configured
+func (mp *ManagerProxy) Enabled() bool {
+	return mp.cConfig.Inboxes["manager"] != ""
+}
+
+// FetchConfiguration fetches configuration of a component from the cluster manager.
+// The last fetched configuration is cached locally and sent as ETag, so an unchanged or
+// unreachable manager yields the cached copy. nil is returned when nothing is published.
+func (mp *ManagerProxy) FetchConfiguration(qualifier string) (*ComponentConfiguration, error) {
+	/*
+		path: /clusters/<>/zones/<>/<componentType>/configurations/<qualifier>
+		sample response (ETag: "42"):
+			{
+				"version" : "42",
+				"configuration" : { ... }
+			}
+	*/
+	configPath := "/clusters/" + mp.cConfig.Cluster + "/zones/" + mp.cConfig.Zone +
+		"/" + mp.cConfig.ComponentType + "/configurations/" + qualifier
+
+	cached := mp.CachedConfiguration(qualifier)
+	headers := map[string]string{}
+	if cached != nil && cached.ETag != "" {
+		headers["If-None-Match"] = cached.ETag
+	}
+
+	res, err := mp.jsonClient.GetResponse(configPath, headers)
+	if err != nil {
+		if cached != nil {
+			managerLog.Infof("manager not reachable, using cached configuration of %s version %s", qualifier, cached.Version)
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusNotModified:
+		return cached, nil
+	case http.StatusNotFound:
+		managerLog.Infof("no configuration published for %s", qualifier)
+		return nil, nil
+	case http.StatusOK:
+	default:
+		if cached != nil {
+			managerLog.Infof("manager responded with %d, using cached configuration of %s version %s", res.StatusCode, qualifier, cached.Version)
+			return cached, nil
+		}
+		return nil, fmt.Errorf("manager responded with %d for configuration of %s", res.StatusCode, qualifier)
+	}
+
+	cc := &ComponentConfiguration{}
+	err = json.Unmarshal(res.Body, cc)
+	if err != nil {
+		managerLog.Errorln(err)
+		return nil, err
+	}
+	cc.Qualifier = qualifier
+	cc.ETag = res.Header.Get("ETag")
+	if cc.ETag == "" {
+		cc.ETag = cc.Version
+	}
+	cc.FetchedTime = time.Now()
+	managerLog.Infof("fetched configuration of %s version %s", qualifier, cc.Version)
+
+	err = mp.saveConfiguration(cc)
+	if err != nil {
+		managerLog.Errorf("unable to cache configuration of %s: %s", qualifier, err)
+	}
+	return cc, nil
+}
+
+// CachedConfiguration returns the locally cached configuration of a component, nil if there is none
+func (mp *ManagerProxy) CachedConfiguration(qualifier string) *ComponentConfiguration {
+	data, err := ioutil.ReadFile(mp.cacheFile(qualifier))
+	if err != nil {
+		return nil
+	}
+	cc := &ComponentConfiguration{}
+	err = json.Unmarshal(data, cc)
+	if err != nil {
+		managerLog.Errorf("ignoring corrupt cached configuration of %s: %s", qualifier, err)
+		return nil
+	}
+	return cc
+}
+
+// Watch polls the manager for a newer configuration of a component,
+// onChange is called whenever the published version differs from the known one
+func (mp *ManagerProxy) Watch(qualifier, version string, interval time.Duration, onChange func(*ComponentConfiguration)) *Watcher {
+	w := &Watcher{
+		stopChan: make(chan struct{}),
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := version
+		for {
+			select {
+			case <-ticker.C:
+				cc, err := mp.FetchConfiguration(qualifier)
+				if err != nil || cc == nil || cc.Version == last {
+					continue
+				}
+				managerLog.Infof("manager published configuration of %s version %s", qualifier, cc.Version)
+				last = cc.Version
+				onChange(cc)
+
+			case <-w.stopChan:
+				return
+			}
+		}
+	}()
+
+	return w
+}
+
+func (mp *ManagerProxy) cacheFile(qualifier string) string {
+	return filepath.Join(mp.cacheDir, qualifier+".config.json")
+}
+
+// saveConfiguration writes the configuration to the local cache
+func (mp *ManagerProxy) saveConfiguration(cc *ComponentConfiguration) error {
+	data, err := json.MarshalIndent(cc, "", " ")
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(mp.cacheFile(cc.Qualifier), data, 0644)
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Instance container instance registered with registry. Ports are numbers or, as the port of a
+// container is configured as a string, numeric strings; json.Number takes both.
+type Instance struct {
+	TmgcId    string      `json:"tmgcId"`
+	Name      string      `json:"name"`
+	Host      string      `json:"host"`
+	Port      json.Number `json:"port"`
+	AgentPort json.Number `json:"agentPort"`
+	Status    string      `json:"status"`
+}
+
+// ListInstances lists instances of a componentType registered in the given cluster and zone
+func (rp *RegistryProxy) ListInstances(cluster, zone, componentType string) ([]Instance, error) {
+	/*
+		path: /clusters/<>/zones/<>/<componentType>
+		sample response:
+			[
+				{
+					"tmgcId" : "9e86528a-f7b1-415a-bbdc-048185395c64",
+					"name" : "cm-node1",
+					"host" : "10.97.90.65",
+					"port" : 21180,
+					"agentPort" : 21780,
+					"status" : "ACTIVE"
+				}
+			]
+	*/
+	listPath := "/clusters/" + cluster + "/zones/" + zone + "/" + componentType
+	res, err := rp.jsonClient.Get(listPath)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := []Instance{}
+	err = json.Unmarshal(res, &instances)
+	if err != nil {
+		log.Errorf("unable to parse instances of %s: %s", componentType, err)
+		return nil, err
+	}
+
+	// keep a stable order so that callers can compare results
+	sort.Slice(instances, func(i, j int) bool { return instances[i].TmgcId < instances[j].TmgcId })
+	return instances, nil
+}
+
+// ListZoneInstances lists instances of a componentType registered in the container's own cluster and zone
+func (rp *RegistryProxy) ListZoneInstances(componentType string) ([]Instance, error) {
+	return rp.ListInstances(rp.cConfig.Cluster, rp.cConfig.Zone, componentType)
+}
+
+// GetInstance fetches a single registered instance
+func (rp *RegistryProxy) GetInstance(cluster, zone, componentType, tmgcId string) (*Instance, error) {
+	instancePath := "/clusters/" + cluster + "/zones/" + zone + "/" + componentType + "/" + tmgcId
+	res, err := rp.jsonClient.Get(instancePath)
+	if err != nil {
+		return nil, err
+	}
+
+	instance := &Instance{}
+	err = json.Unmarshal(res, instance)
+	if err != nil {
+		log.Errorf("unable to parse instance %s of %s: %s", tmgcId, componentType, err)
+		return nil, err
+	}
+	return instance, nil
+}
+
+// Watcher polls registry for changes of a componentType
+type Watcher struct {
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// Watch polls the instances of a componentType in the container's cluster and zone,
+// onChange is called with the new instances whenever they differ from the known ones
+func (rp *RegistryProxy) Watch(componentType string, known []Instance, interval time.Duration, onChange func([]Instance)) *Watcher {
+	w := &Watcher{
+		stopChan: make(chan struct{}),
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := known
+		for {
+			select {
+			case <-ticker.C:
+				instances, err := rp.ListZoneInstances(componentType)
+				if err != nil {
+					continue
+				}
+				if (len(last) == 0 && len(instances) == 0) || reflect.DeepEqual(last, instances) {
+					continue
+				}
+				log.Infof("instances of %s changed", componentType)
+				last = instances
+				onChange(instances)
+
+			case <-w.stopChan:
+				return
+			}
+		}
+	}()
+
+	return w
+}
+
+// Stop stops watching
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() { close(w.stopChan) })
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestListZoneInstances(t *testing.T) {
+	registry := newFakeRegistry()
+	defer registry.Close()
+	// agents register the port of their container as configured, a number or a string
+	registry.responses["/clusters/cluster/zones/zone/trafficmanagers"] = `[
+		{"tmgcId": "tm-2", "name": "tm-node", "host": "10.0.0.2", "port": "9080", "agentPort": 21780, "status": "ACTIVE"},
+		{"tmgcId": "tm-1", "name": "tm-node", "host": "10.0.0.1", "port": 9080, "agentPort": "21780", "status": "ACTIVE"}
+	]`
+	rp := NewRegistryProxyService(testContainer(registry.URL))
+
+	instances, err := rp.ListZoneInstances("trafficmanagers")
+	if err != nil {
+		t.Fatalf("ListZoneInstances() error = %v", err)
+	}
+	if len(instances) != 2 || instances[0].TmgcId != "tm-1" || instances[1].TmgcId != "tm-2" {
+		t.Fatalf("instances = %+v, want tm-1 and tm-2 in order", instances)
+	}
+	for _, instance := range instances {
+		if instance.Port.String() != "9080" || instance.AgentPort.String() != "21780" {
+			t.Errorf("%s ports = %s %s, want 9080 21780", instance.TmgcId, instance.Port, instance.AgentPort)
+		}
+	}
+
+	if _, err := rp.ListZoneInstances("collectors"); err == nil {
+		t.Error("ListZoneInstances() of an unparseable response succeeded")
+	}
+}
+
+func TestWatchReportsChanges(t *testing.T) {
+	registry := newFakeRegistry()
+	defer registry.Close()
+	path := "/clusters/cluster/zones/zone/trafficmanagers"
+	registry.responses[path] = `[{"tmgcId": "tm-1", "port": 9080}]`
+	rp := NewRegistryProxyService(testContainer(registry.URL))
+	known, _ := rp.ListZoneInstances("trafficmanagers")
+
+	changes := make(chan []Instance, 10)
+	w := rp.Watch("trafficmanagers", known, 5*time.Millisecond, func(instances []Instance) { changes <- instances })
+	defer w.Stop()
+
+	time.Sleep(30 * time.Millisecond)
+	if len(changes) != 0 {
+		t.Fatalf("reported %d changes of unchanged instances", len(changes))
+	}
+
+	registry.mutex.Lock()
+	registry.responses[path] = `[{"tmgcId": "tm-1", "port": 9080}, {"tmgcId": "tm-2", "port": 9080}]`
+	registry.mutex.Unlock()
+	select {
+	case instances := <-changes:
+		if len(instances) != 2 {
+			t.Errorf("reported %d instances, want 2", len(instances))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the new instance was not reported")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if len(changes) != 0 {
+		t.Errorf("reported the same change %d more times", len(changes))
+	}
+}
@@ -2,9 +2,11 @@ package service
 
 import (
 	"encoding/json"
+	"net/http"
 
 	"github.com/rameshpolishetti/mlca/internal/core/common/config"
 	jsonclient "github.com/rameshpolishetti/mlca/internal/core/common/restclient"
+	"github.com/rameshpolishetti/mlca/internal/core/common/version"
 	"github.com/rameshpolishetti/mlca/logger"
 )
 
@@ -12,16 +14,22 @@ var log = logger.GetLogger("registry-service")
 
 // RegistryProxy rigistry
 type RegistryProxy struct {
-	cConfig    config.ContainerDaemon
-	jsonClient *jsonclient.JSONClient
+	cConfig     config.ContainerDaemon
+	jsonClient  *jsonclient.JSONClient
+	registryURL string
 
 	// registry status
-	isReady bool
+	isReady      bool
+	isRegistered bool
+	// registration restored from a previous agent run, verified before it is reused
+	restored bool
 
 	// cluster info
 	tmgcId    string
 	zoneId    string
 	clusterId string
+	domainId  string
+	nodeId    string
 }
 
 // NewRegistryProxyService creates new registry proxy
@@ -29,42 +37,38 @@ func NewRegistryProxyService(cCfg config.ContainerDaemon) *RegistryProxy {
 	registry := cCfg.Inboxes["registry"]
 	registryContext := "/registry/rest/v1"
 	rp := &RegistryProxy{
-		cConfig:    cCfg,
-		jsonClient: jsonclient.New(registry, registryContext),
+		cConfig:     cCfg,
+		jsonClient:  jsonclient.New(registry, registryContext),
+		registryURL: registry + registryContext,
 	}
 	return rp
 }
 
-// Register rigister with rigistry
+// Register rigister the container with rigistry, the container is registered only once
 func (rp *RegistryProxy) Register() bool {
+	if rp.isRegistered {
+		return true
+	}
+
 	// check whether the registry is ready
 	if !rp.IsReady() {
 		log.Infoln("Registry is not ready")
 		return false
 	}
 
-	registerPath := "/clusters/" + rp.cConfig.Cluster + "/zones/" + rp.cConfig.Zone + "/" + rp.cConfig.ComponentType
-	log.Infoln("Registering")
-	/**
-	 * create registry payload
-	 * {
-	 * "name": "proxy-node1",
-	 * "host": "10.1.2.3",
-	 * "port": 9096,
-	 * "agentPort": 1234,
-	 * "status": "registering",
-	 * ... plus container specific arguments
-	 * }
-	 */
-	payloadMap := map[string]interface{}{
-		"name":      rp.cConfig.Name,
-		"host":      rp.cConfig.IP,
-		"port":      rp.cConfig.Port,
-		"agentPort": rp.cConfig.TransportSettings.Port,
-		"status":    "registering",
+	if rp.restored && rp.tmgcId != "" {
+		registered, ok := rp.verifyRegistration()
+		if !ok {
+			return false
+		}
+		if registered {
+			rp.isRegistered = true
+			return true
+		}
 	}
 
-	res, err := rp.jsonClient.Post(registerPath, payloadMap)
+	log.Infoln("Registering")
+	res, err := rp.jsonClient.Post(rp.registrationPath(), rp.registrationPayload())
 	if err != nil {
 		return false
 	}
@@ -82,10 +86,12 @@ func (rp *RegistryProxy) Register() bool {
 	}
 	*/
 	type RegistryResp struct {
-		TmgcId    string `json:tmgcId`
-		ZoneId    string `json:zoneId`
-		ClusterId string `json:clusterId`
-		Status    string `json:status`
+		TmgcId    string `json:"tmgcId"`
+		ZoneId    string `json:"zoneId"`
+		ClusterId string `json:"clusterId"`
+		DomainId  string `json:"domainId"`
+		NodeId    string `json:"nodeId"`
+		Status    string `json:"status"`
 	}
 	respObj := &RegistryResp{}
 	err = json.Unmarshal(res, respObj)
@@ -98,11 +104,167 @@ func (rp *RegistryProxy) Register() bool {
 		rp.tmgcId = respObj.TmgcId
 		rp.zoneId = respObj.ZoneId
 		rp.clusterId = respObj.ClusterId
+		rp.domainId = respObj.DomainId
+		rp.nodeId = respObj.NodeId
+		rp.isRegistered = true
 		return true
 	}
 	return false
 }
 
+// registrationPath path of the collection the container registers in
+func (rp *RegistryProxy) registrationPath() string {
+	return "/clusters/" + rp.cConfig.Cluster + "/zones/" + rp.cConfig.Zone + "/" + rp.cConfig.ComponentType
+}
+
+func (rp *RegistryProxy) registrationPayload() map[string]interface{} {
+	/**
+	 * create registry payload
+	 * {
+	 * "name": "proxy-node1",
+	 * "host": "10.1.2.3",
+	 * "port": 9096,
+	 * "agentPort": 1234,
+	 * "status": "registering",
+	 * "version": "1.0.0",
+	 * ... plus container specific arguments
+	 * }
+	 */
+	return map[string]interface{}{
+		"name":      rp.cConfig.Name,
+		"host":      rp.cConfig.IP,
+		"port":      rp.cConfig.Port,
+		"agentPort": rp.cConfig.TransportSettings.Port,
+		"status":    "registering",
+		"version":   version.Version,
+	}
+}
+
+// Registration returns the url and payload the container is registered with, without contacting registry
+func (rp *RegistryProxy) Registration() (string, map[string]interface{}) {
+	return rp.registryURL + rp.registrationPath(), rp.registrationPayload()
+}
+
+// Restore reuses the registration of a previous agent run, it is verified with registry on the next Register
+func (rp *RegistryProxy) Restore(tmgcId, zoneId, clusterId string) {
+	if rp.isRegistered || tmgcId == "" {
+		return
+	}
+	rp.tmgcId = tmgcId
+	rp.zoneId = zoneId
+	rp.clusterId = clusterId
+	rp.restored = true
+}
+
+// RegistrationRestored returns whether the container registration was restored from a previous agent run,
+// registrations of its components are then still valid
+func (rp *RegistryProxy) RegistrationRestored() bool {
+	return rp.isRegistered && rp.restored
+}
+
+// verifyRegistration checks whether the restored registration still exists, ok is false when registry
+// could not tell. A registration that no longer exists is dropped.
+func (rp *RegistryProxy) verifyRegistration() (registered bool, ok bool) {
+	res, err := rp.jsonClient.GetResponse(rp.containerPath(), nil)
+	if err != nil {
+		return false, false
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		log.Infof("Reusing the registration %s of the previous agent run", rp.tmgcId)
+		return true, true
+	case http.StatusNotFound:
+		log.Infof("Registration %s of the previous agent run no longer exists, registering again", rp.tmgcId)
+		rp.tmgcId = ""
+		rp.zoneId = ""
+		rp.clusterId = ""
+		rp.restored = false
+		return false, true
+	}
+	log.Errorf("unable to verify registration %s: status %d", rp.tmgcId, res.StatusCode)
+	return false, false
+}
+
+// IsRegistered return whether the container is registered with registry
+func (rp *RegistryProxy) IsRegistered() bool {
+	return rp.isRegistered
+}
+
+// ContainerInstance returns the container identity, including the ids assigned by registry once registered
+func (rp *RegistryProxy) ContainerInstance() config.ContainerInstance {
+	ci := rp.cConfig.ContainerInstance()
+	if rp.isRegistered {
+		ci.TmgcID = rp.tmgcId
+		ci.ZoneID = rp.zoneId
+		ci.ClusterID = rp.clusterId
+		ci.DomainID = rp.domainId
+		ci.NodeID = rp.nodeId
+		ci.RegistryContext = rp.registryURL + rp.containerPath()
+	}
+	return ci
+}
+
+// RegisterComponent registers a managed component as sub-resource of the container and returns its component id
+func (rp *RegistryProxy) RegisterComponent(mc config.ManagedComponent) (string, bool) {
+	if !rp.Register() {
+		return "", false
+	}
+	/*
+		payload: {"name":"TMG-Microgateway","type":"Microgateway","qualifier":"microgateway","service":"...","status":"registering"}
+		path: /clusters/<>/zones/<>/containerName/<>/components
+		sample response:
+			{
+				"componentId" : "0c1a8f3e-5d0b-4cbe-9a55-5b7f2f2f8d21",
+				"tmgcId" : "d530176c-d85c-4160-b18f-f46377f104bf",
+				"name" : "TMG-Microgateway",
+				"status" : "registered"
+			}
+	*/
+	log.Infof("Registering component [%s]", mc.Name)
+	res, err := rp.jsonClient.Post(rp.containerPath()+"/components", componentPayload(mc))
+	if err != nil {
+		return "", false
+	}
+	log.Infof("Response from registry: %s \n", res)
+
+	type RegistryResp struct {
+		ComponentId string `json:"componentId"`
+		Status      string `json:"status"`
+	}
+	respObj := &RegistryResp{}
+	err = json.Unmarshal(res, respObj)
+	if err != nil {
+		log.Errorln(err)
+		return "", false
+	}
+
+	if respObj.Status == "registered" && respObj.ComponentId != "" {
+		return respObj.ComponentId, true
+	}
+	return "", false
+}
+
+func componentPayload(mc config.ManagedComponent) map[string]interface{} {
+	return map[string]interface{}{
+		"name":      mc.Name,
+		"type":      mc.Type,
+		"qualifier": mc.Qualifier,
+		"service":   mc.Service,
+		"status":    "registering",
+	}
+}
+
+// ComponentRegistration returns the url and payload a managed component is registered with, without
+// contacting registry; the ids registry assigns to the container are shown as placeholders until it is registered
+func (rp *RegistryProxy) ComponentRegistration(mc config.ManagedComponent) (string, map[string]interface{}) {
+	path := rp.containerPath()
+	if !rp.isRegistered {
+		path = "/clusters/{clusterId}/zones/{zoneId}/" + rp.cConfig.ComponentType + "/{tmgcId}"
+	}
+	return rp.registryURL + path + "/components", componentPayload(mc)
+}
+
 // IsReady return whether registry is ready
 func (rp *RegistryProxy) IsReady() bool {
 	if rp.isReady {
@@ -133,10 +295,10 @@ func (rp *RegistryProxy) IsReady() bool {
 	return rp.isReady
 }
 
-// UpdateStatus updates status with registry
+// UpdateStatus updates container status with registry
 func (rp *RegistryProxy) UpdateStatus(status string) bool {
 	// check whether the registry is ready
-	if !rp.IsReady() {
+	if !rp.IsReady() || !rp.isRegistered {
 		log.Infoln("Registry is not ready")
 		return false
 	}
@@ -154,10 +316,7 @@ func (rp *RegistryProxy) UpdateStatus(status string) bool {
 				"status" : "UNSATISFIED"
 			}
 	*/
-	updateStatusPath := "/clusters/" + rp.clusterId +
-		"/zones/" + rp.zoneId +
-		"/" + rp.cConfig.ComponentType + "/" + rp.tmgcId +
-		"/status"
+	updateStatusPath := rp.containerPath() + "/status"
 	log.Infoln("PUT request to: ", updateStatusPath)
 
 	payloadMap := map[string]interface{}{
@@ -172,3 +331,51 @@ func (rp *RegistryProxy) UpdateStatus(status string) bool {
 
 	return true
 }
+
+// UpdateComponentStatus updates status of a registered managed component with registry
+func (rp *RegistryProxy) UpdateComponentStatus(componentID, status string) bool {
+	if !rp.IsReady() || !rp.isRegistered || componentID == "" {
+		log.Infoln("Component is not registered")
+		return false
+	}
+	/*
+		payload: {"status":"ACTIVE"}
+		path: /clusters/<>/zones/<>/containerName/<>/components/<>/status
+	*/
+	updateStatusPath := rp.containerPath() + "/components/" + componentID + "/status"
+	log.Infoln("PUT request to: ", updateStatusPath)
+
+	payloadMap := map[string]interface{}{
+		"status": status,
+	}
+
+	res, err := rp.jsonClient.Put(updateStatusPath, payloadMap)
+	if err != nil {
+		return false
+	}
+	log.Infof("Updated component status in registry to %s - Response from registry: %s", status, res)
+
+	return true
+}
+
+// DeregisterComponent removes a managed component from registry
+func (rp *RegistryProxy) DeregisterComponent(componentID string) bool {
+	if !rp.isRegistered || componentID == "" {
+		return false
+	}
+
+	deregisterPath := rp.containerPath() + "/components/" + componentID
+	res, err := rp.jsonClient.Delete(deregisterPath)
+	if err != nil {
+		return false
+	}
+	log.Infof("Deregistered component %s - Response from registry: %s", componentID, res)
+	return true
+}
+
+// containerPath returns registry path of the registered container
+func (rp *RegistryProxy) containerPath() string {
+	return "/clusters/" + rp.clusterId +
+		"/zones/" + rp.zoneId +
+		"/" + rp.cConfig.ComponentType + "/" + rp.tmgcId
+}
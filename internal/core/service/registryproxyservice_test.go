@@ -0,0 +1,132 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rameshpolishetti/mlca/internal/core/common/config"
+)
+
+// fakeRegistry registry answering like the registry service, it records the requests it served
+type fakeRegistry struct {
+	*httptest.Server
+	mutex      sync.Mutex
+	requests   []string
+	payloads   map[string][]map[string]interface{}
+	components int
+	// responses to GET requests by path, e.g. the instances of a componentType
+	responses map[string]string
+}
+
+func newFakeRegistry() *fakeRegistry {
+	fr := &fakeRegistry{
+		payloads:  make(map[string][]map[string]interface{}),
+		responses: make(map[string]string),
+	}
+	fr.Server = httptest.NewServer(http.HandlerFunc(fr.serve))
+	return fr
+}
+
+func (fr *fakeRegistry) serve(w http.ResponseWriter, r *http.Request) {
+	fr.mutex.Lock()
+	defer fr.mutex.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/registry/rest/v1")
+	fr.requests = append(fr.requests, r.Method+" "+path)
+	if body, _ := ioutil.ReadAll(r.Body); len(body) > 0 {
+		payload := map[string]interface{}{}
+		json.Unmarshal(body, &payload)
+		fr.payloads[path] = append(fr.payloads[path], payload)
+	}
+
+	switch {
+	case r.Method == http.MethodGet && path == "/status":
+		fmt.Fprint(w, `{"status":"REGISTRY_READY"}`)
+	case r.Method == http.MethodGet:
+		fmt.Fprint(w, fr.responses[path])
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/components"):
+		fr.components++
+		fmt.Fprintf(w, `{"componentId":"component-%d","tmgcId":"tm-1","status":"registered"}`, fr.components)
+	case r.Method == http.MethodPost:
+		fmt.Fprint(w, `{"tmgcId":"tm-1","zoneId":"zone-1","clusterId":"cluster-1","status":"registered"}`)
+	default:
+		fmt.Fprint(w, `{}`)
+	}
+}
+
+// served returns the requests served so far, e.g. "POST /clusters/cluster/zones/zone/trafficmanagers"
+func (fr *fakeRegistry) served() []string {
+	fr.mutex.Lock()
+	defer fr.mutex.Unlock()
+	return append([]string(nil), fr.requests...)
+}
+
+func testContainer(registry string) config.ContainerDaemon {
+	return config.ContainerDaemon{
+		Name:              "mashling",
+		ComponentType:     "trafficmanagers",
+		Cluster:           "cluster",
+		Zone:              "zone",
+		Inboxes:           map[string]string{"registry": registry},
+		TransportSettings: config.TransportSettings{Port: 21780},
+	}
+}
+
+func TestRegisterComponentsAsSubResources(t *testing.T) {
+	registry := newFakeRegistry()
+	defer registry.Close()
+	rp := NewRegistryProxyService(testContainer(registry.URL))
+
+	gw, ok := rp.RegisterComponent(config.ManagedComponent{Name: "TMG-Microgateway", Type: "Microgateway", Qualifier: "microgateway"})
+	if !ok {
+		t.Fatal("registration of TMG-Microgateway failed")
+	}
+	lfa, ok := rp.RegisterComponent(config.ManagedComponent{Name: "TMG-LFA", Type: "Log", Qualifier: "lfa"})
+	if !ok {
+		t.Fatal("registration of TMG-LFA failed")
+	}
+	if gw == lfa {
+		t.Errorf("both components got the id %s", gw)
+	}
+	rp.UpdateComponentStatus(gw, "ACTIVE")
+	rp.UpdateStatus("ACTIVE")
+	rp.DeregisterComponent(lfa)
+
+	// the container registers once, its components below its own registration
+	container := "/clusters/cluster-1/zones/zone-1/trafficmanagers/tm-1"
+	want := []string{
+		"GET /status",
+		"POST /clusters/cluster/zones/zone/trafficmanagers",
+		"POST " + container + "/components",
+		"POST " + container + "/components",
+		"PUT " + container + "/components/" + gw + "/status",
+		"PUT " + container + "/status",
+		"DELETE " + container + "/components/" + lfa,
+	}
+	if got := registry.served(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("registry served\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+
+	payloads := registry.payloads[container+"/components"]
+	if len(payloads) != 2 || payloads[0]["name"] != "TMG-Microgateway" || payloads[0]["type"] != "Microgateway" || payloads[1]["qualifier"] != "lfa" {
+		t.Errorf("component payloads = %v", payloads)
+	}
+	if status := registry.payloads[container+"/components/"+gw+"/status"]; len(status) != 1 || status[0]["status"] != "ACTIVE" {
+		t.Errorf("component status payload = %v", status)
+	}
+}
+
+func TestComponentsAreNotRegisteredBeforeTheContainer(t *testing.T) {
+	rp := NewRegistryProxyService(testContainer("http://127.0.0.1:1"))
+	if _, ok := rp.RegisterComponent(config.ManagedComponent{Name: "TMG-LFA"}); ok {
+		t.Error("a component registered while the registry is not reachable")
+	}
+	if rp.UpdateComponentStatus("component-1", "ACTIVE") || rp.DeregisterComponent("component-1") {
+		t.Error("a component status was sent before the container is registered")
+	}
+}
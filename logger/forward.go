@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultForwardTag tag of the records sent to fluentd/fluent-bit, matched by the
+// default mlca.* outputs of the log forwarding component
+const DefaultForwardTag = "mlca.agent"
+
+// forwardWriter sends entries as records over the fluent forward protocol (message mode)
+type forwardWriter struct {
+	tag  string
+	conn *netConn
+}
+
+func newForwardWriter(o SinkOptions) (*forwardWriter, error) {
+	if o.Address == "" {
+		return nil, fmt.Errorf("forward sink requires an address")
+	}
+	tag := o.Tag
+	if tag == "" {
+		tag = DefaultForwardTag
+	}
+	return &forwardWriter{
+		tag: tag,
+		conn: &netConn{dial: func() (net.Conn, error) {
+			return net.DialTimeout("tcp", o.Address, dialTimeout)
+		}},
+	}, nil
+}
+
+func (fw *forwardWriter) write(name string, entry *logrus.Entry, line []byte) error {
+	// [tag, time, record]
+	message := []interface{}{fw.tag, entry.Time.Unix(), entryRecord(name, entry)}
+	return fw.conn.send(appendMsgpack(nil, message))
+}
+
+func (fw *forwardWriter) close() error {
+	return fw.conn.close()
+}
+
+// appendMsgpack appends the msgpack encoding of v, values of unsupported types are encoded as strings
+func appendMsgpack(b []byte, v interface{}) []byte {
+	switch t := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if t {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case string:
+		return appendMsgpackString(b, t)
+	case float32:
+		return appendMsgpackFloat(b, float64(t))
+	case float64:
+		return appendMsgpackFloat(b, t)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b = appendMsgpackHeader(b, len(keys), 0x80, 16, 0xde, 0xdf)
+		for _, k := range keys {
+			b = appendMsgpackString(b, k)
+			b = appendMsgpack(b, t[k])
+		}
+		return b
+	case []interface{}:
+		b = appendMsgpackHeader(b, len(t), 0x90, 16, 0xdc, 0xdd)
+		for _, e := range t {
+			b = appendMsgpack(b, e)
+		}
+		return b
+	case error:
+		return appendMsgpackString(b, t.Error())
+	case fmt.Stringer:
+		return appendMsgpackString(b, t.String())
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendMsgpackInt(b, rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := rv.Uint()
+		if u <= math.MaxInt64 {
+			return appendMsgpackInt(b, int64(u))
+		}
+		b = append(b, 0xcf)
+		return appendUint64(b, u)
+	}
+	return appendMsgpackString(b, fmt.Sprint(v))
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xda, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0xdb)
+		b = appendUint32(b, uint32(n))
+	}
+	return append(b, s...)
+}
+
+func appendMsgpackInt(b []byte, i int64) []byte {
+	if i >= 0 && i < 128 || i < 0 && i >= -32 {
+		return append(b, byte(i))
+	}
+	b = append(b, 0xd3)
+	return appendUint64(b, uint64(i))
+}
+
+func appendMsgpackFloat(b []byte, f float64) []byte {
+	b = append(b, 0xcb)
+	return appendUint64(b, math.Float64bits(f))
+}
+
+// appendMsgpackHeader appends a map or array header in its fix, 16 or 32 bit form
+func appendMsgpackHeader(b []byte, n int, fix byte, fixMax int, code16, code32 byte) []byte {
+	switch {
+	case n < fixMax:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, code16, byte(n>>8), byte(n))
+	}
+	b = append(b, code32)
+	return appendUint32(b, uint32(n))
+}
+
+func appendUint32(b []byte, u uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], u)
+	return append(b, buf[:]...)
+}
+
+func appendUint64(b []byte, u uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], u)
+	return append(b, buf[:]...)
+}
@@ -0,0 +1,199 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// readMsgpack decodes one msgpack value of the types written by appendMsgpack, integers are
+// decoded as int64 and unsigned integers beyond int64 as uint64
+func readMsgpack(r *bufio.Reader) (interface{}, error) {
+	code, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xe0 == 0xa0:
+		return readString(r, int(code&0x1f))
+	case code&0xf0 == 0x90:
+		return readArray(r, int(code&0x0f))
+	case code&0xf0 == 0x80:
+		return readMap(r, int(code&0x0f))
+	}
+
+	var n uint64
+	size := map[byte]int{0xd9: 1, 0xda: 2, 0xdb: 4, 0xdc: 2, 0xdd: 4, 0xde: 2, 0xdf: 4, 0xd3: 8, 0xcf: 8, 0xcb: 8}[code]
+	for i := 0; i < size; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		n = n<<8 | uint64(b)
+	}
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2, 0xc3:
+		return code == 0xc3, nil
+	case 0xd9, 0xda, 0xdb:
+		return readString(r, int(n))
+	case 0xdc, 0xdd:
+		return readArray(r, int(n))
+	case 0xde, 0xdf:
+		return readMap(r, int(n))
+	case 0xd3:
+		return int64(n), nil
+	case 0xcf:
+		return n, nil
+	case 0xcb:
+		return math.Float64frombits(n), nil
+	}
+	return nil, fmt.Errorf("unexpected msgpack code %#x", code)
+}
+
+func readString(r *bufio.Reader, n int) (interface{}, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(r, b)
+	return string(b), err
+}
+
+func readArray(r *bufio.Reader, n int) (interface{}, error) {
+	a := make([]interface{}, n)
+	for i := range a {
+		v, err := readMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		a[i] = v
+	}
+	return a, nil
+}
+
+func readMap(r *bufio.Reader, n int) (interface{}, error) {
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := readMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		v, err := readMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		m[fmt.Sprint(k)] = v
+	}
+	return m, nil
+}
+
+func TestForwardSink(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := NewSink(SinkOptions{Type: SinkForward, Address: l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	s.write("TMG-LFA", &logrus.Entry{Time: now, Level: logrus.WarnLevel, Message: "restarting", Data: logrus.Fields{
+		"component": "TMG-LFA",
+		"pid":       4242,
+		"error":     fmt.Errorf("exited"),
+		"logger":    "shadowed",
+	}})
+	s.write("cagent", &logrus.Entry{Time: now, Level: logrus.InfoLevel, Message: strings.Repeat("x", 300)})
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+
+	// each entry is a [tag, time, record] message
+	var records []map[string]interface{}
+	for i := 0; i < 2; i++ {
+		v, err := readMsgpack(r)
+		if err != nil {
+			t.Fatalf("message %d: %s", i, err)
+		}
+		message, ok := v.([]interface{})
+		if !ok || len(message) != 3 || message[0] != DefaultForwardTag || message[1] != now.Unix() {
+			t.Fatalf("message %d = %v, want [%s %d record]", i, v, DefaultForwardTag, now.Unix())
+		}
+		records = append(records, message[2].(map[string]interface{}))
+	}
+
+	for k, want := range map[string]interface{}{
+		"logger":        "TMG-LFA",
+		"level":         "WARN",
+		"message":       "restarting",
+		"process":       "TMG-LFA",
+		"pid":           int64(4242),
+		"error":         "exited",
+		"fields.logger": "shadowed",
+	} {
+		if records[0][k] != want {
+			t.Errorf("record[%s] = %v, want %v", k, records[0][k], want)
+		}
+	}
+	if records[1]["process"] != agentProcess || records[1]["message"] != strings.Repeat("x", 300) {
+		t.Errorf("second record = %v", records[1])
+	}
+	s.Close()
+}
+
+func TestAppendMsgpackBoundaries(t *testing.T) {
+	// the smallest encoding is chosen at the boundaries of each form
+	for v, want := range map[interface{}]string{
+		127:                     "7f",
+		128:                     "d30000000000000080",
+		-32:                     "e0",
+		-33:                     "d3ffffffffffffffdf",
+		uint64(math.MaxUint64):  "cfffffffffffffffff",
+		strings.Repeat("a", 31): "bf" + strings.Repeat("61", 31),
+		strings.Repeat("a", 32): "d920" + strings.Repeat("61", 32),
+		time.Second:             "a23173",
+	} {
+		if got := hex.EncodeToString(appendMsgpack(nil, v)); got != want {
+			t.Errorf("appendMsgpack(%v) = %s, want %s", v, got, want)
+		}
+	}
+
+	// values round trip through the decoder, including the 16 bit headers
+	long := make([]interface{}, 20)
+	for i := range long {
+		long[i] = int64(i * 1000)
+	}
+	wide := make(map[string]interface{})
+	for i := 0; i < 20; i++ {
+		wide[fmt.Sprintf("k%02d", i)] = []interface{}{true, nil, 1.5}
+	}
+	for _, v := range []interface{}{long, wide, strings.Repeat("b", 70000)} {
+		b := appendMsgpack(nil, v)
+		got, err := readMsgpack(bufio.NewReader(strings.NewReader(string(b))))
+		if err != nil || fmt.Sprint(got) != fmt.Sprint(v) {
+			t.Errorf("decoded %.60v, %v, want %.60v", got, err, v)
+		}
+	}
+	if b := appendMsgpack(nil, long); binary.BigEndian.Uint16(b[1:3]) != 20 || b[0] != 0xdc {
+		t.Errorf("array of 20 has header % x, want dc 00 14", b[:3])
+	}
+}
@@ -1,29 +1,239 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
-	"log/syslog"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
-	lSyslog "github.com/sirupsen/logrus/hooks/syslog"
 )
 
-var logLevel = logrus.DebugLevel
+// log line formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// FormatEnv environment variable selecting the log format before the configuration is loaded
+const FormatEnv = "MLCA_LOGGING_FORMAT"
+
+// agentProcess process the lines of the agent loggers are tagged with
+const agentProcess = "containeragent"
+
+// TimestampFormat RFC 3339 with milliseconds
+const TimestampFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// format of the log lines of every logger, switched at runtime
+var format = struct {
+	sync.RWMutex
+	name   string
+	utc    bool
+	caller bool
+}{
+	name: defaultFormat(),
+}
+
+func defaultFormat() string {
+	if os.Getenv(FormatEnv) == FormatJSON {
+		return FormatJSON
+	}
+	return FormatText
+}
+
+// SetFormat switches the format of all loggers, empty selects the text format
+func SetFormat(name string) error {
+	switch name {
+	case "":
+		name = FormatText
+	case FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("unknown log format [%s], must be one of %s, %s", name, FormatText, FormatJSON)
+	}
+	format.Lock()
+	format.name = name
+	format.Unlock()
+	return nil
+}
+
+func currentFormat() string {
+	format.RLock()
+	defer format.RUnlock()
+	return format.name
+}
+
+// SetUTC writes timestamps in UTC instead of local time
+func SetUTC(utc bool) {
+	format.Lock()
+	format.utc = utc
+	format.Unlock()
+}
+
+// SetReportCaller adds the file:line of the logging call to every line
+func SetReportCaller(caller bool) {
+	format.Lock()
+	format.caller = caller
+	format.Unlock()
+
+	registry.Lock()
+	defer registry.Unlock()
+	for _, logger := range registry.loggers {
+		logger.SetReportCaller(caller)
+	}
+}
+
+func reportCaller() bool {
+	format.RLock()
+	defer format.RUnlock()
+	return format.caller
+}
+
+// timestamp formats t in the TimestampFormat, in UTC when configured
+func timestamp(t time.Time) string {
+	format.RLock()
+	utc := format.utc
+	format.RUnlock()
+	if utc {
+		t = t.UTC()
+	}
+	return t.Format(TimestampFormat)
+}
+
+// caller returns the package directory, file and line of the logging call, empty when not reported
+func caller(entry *logrus.Entry) string {
+	if entry.Caller == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s:%d", filepath.Base(filepath.Dir(entry.Caller.File)), filepath.Base(entry.Caller.File), entry.Caller.Line)
+}
+
+// metadata every log line is tagged with, defaults to the pod environment
+var metadata = struct {
+	cluster string
+	zone    string
+	podIP   string
+}{
+	cluster: os.Getenv("TMG_CLUSTER_NAME"),
+	zone:    os.Getenv("TMG_ZONE_NAME"),
+	podIP:   os.Getenv("POD_IP"),
+}
+
+// SetMetadata sets the cluster, zone and pod ip every log line is tagged with,
+// empty values keep the TMG_CLUSTER_NAME, TMG_ZONE_NAME and POD_IP environment defaults
+func SetMetadata(cluster, zone, podIP string) {
+	if cluster != "" {
+		metadata.cluster = cluster
+	}
+	if zone != "" {
+		metadata.zone = zone
+	}
+	if podIP != "" {
+		metadata.podIP = podIP
+	}
+}
 
 type logFormatter struct {
 	name string
 }
 
 func (lf *logFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	return formatEntry(lf.name, currentFormat(), entry)
+}
 
-	logEntry := fmt.Sprintf("[metadata={process='containeragent',function='containeragent',TMG_CLUSTER_NAME='%s',TMG_ZONE_NAME='%s',POD_IP='%s'}", os.Getenv("TMG_CLUSTER_NAME"), os.Getenv("TMG_ZONE_NAME"), os.Getenv("POD_IP")) + fmt.Sprintf("] [%-5s] [microgateway] %s - %s\n", getLevel(entry.Level), lf.name, entry.Message)
+// formatEntry formats an entry of the named logger in the text or json format
+func formatEntry(name, format string, entry *logrus.Entry) ([]byte, error) {
+	if format == FormatJSON {
+		return formatJSON(name, entry)
+	}
 
-	return []byte(logEntry), nil
+	process, tag := entryTags(entry)
+	logEntry := timestamp(entry.Time) + " " + fmt.Sprintf("[metadata={process='%s',function='%s',TMG_CLUSTER_NAME='%s',TMG_ZONE_NAME='%s',POD_IP='%s'}", process, name, metadata.cluster, metadata.zone, metadata.podIP) + fmt.Sprintf("] [%-5s] [%s] %s", getLevel(entry.Level), tag, name)
+	if c := caller(entry); c != "" {
+		logEntry += " " + c
+	}
+	logEntry += " - " + entry.Message
+	if len(entry.Data) > 0 {
+		logEntry += " " + formatFields(entry.Data)
+	}
+
+	return []byte(logEntry + "\n"), nil
+}
+
+// entryTags returns the process and the tag of an entry: the component and its qualifier, or its
+// name without one, for the entries of a component logger, the container agent for the other loggers
+func entryTags(entry *logrus.Entry) (string, string) {
+	process := agentProcess
+	if component, ok := entry.Data["component"].(string); ok && component != "" {
+		process = component
+	}
+	if qualifier, ok := entry.Data["qualifier"].(string); ok && qualifier != "" {
+		return process, qualifier
+	}
+	return process, process
+}
+
+// formatFields formats entry fields as {key=value ...} sorted by key
+func formatFields(data logrus.Fields) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]string, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, fmt.Sprintf("%s=%v", k, data[k]))
+	}
+	return "{" + strings.Join(fields, " ") + "}"
+}
+
+// entryRecord returns the standard keys and every field of an entry, fields clashing
+// with the standard keys are prefixed with "fields."
+func entryRecord(name string, entry *logrus.Entry) map[string]interface{} {
+	process, _ := entryTags(entry)
+	record := map[string]interface{}{
+		"timestamp":        timestamp(entry.Time),
+		"level":            getLevel(entry.Level),
+		"logger":           name,
+		"message":          entry.Message,
+		"process":          process,
+		"TMG_CLUSTER_NAME": metadata.cluster,
+		"TMG_ZONE_NAME":    metadata.zone,
+		"POD_IP":           metadata.podIP,
+	}
+	if c := caller(entry); c != "" {
+		record["caller"] = c
+	}
+	for k, v := range entry.Data {
+		if _, ok := record[k]; ok {
+			k = "fields." + k
+		}
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		record[k] = v
+	}
+	return record
+}
+
+// formatJSON formats an entry as a single line json object
+func formatJSON(name string, entry *logrus.Entry) ([]byte, error) {
+	serialized, err := json.Marshal(entryRecord(name, entry))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal log entry: %s", err)
+	}
+	return append(serialized, '\n'), nil
 }
 
 func getLevel(level logrus.Level) string {
 	switch level {
+	case logrus.TraceLevel:
+		return "TRACE"
 	case logrus.DebugLevel:
 		return "DEBUG"
 	case logrus.InfoLevel:
@@ -40,18 +250,77 @@ func getLevel(level logrus.Level) string {
 	return "UNKNOWN"
 }
 
-// GetLogger returs logger
+// GetLogger returns the logger registered with the name, creating it on first use
 func GetLogger(loggerName string) *logrus.Logger {
-	logger := logrus.New()
+	return getLogger(loggerName, nil)
+}
 
-	hook, err := lSyslog.NewSyslogHook("", "", syslog.LOG_INFO, "go-mlca")
-	if err == nil {
-		logger.Hooks.Add(hook)
+// GetContextLogger returns the logger registered with the name, creating it on first use.
+// The fields returned by context are added to every entry when it is logged, fields set on
+// the entry take precedence. Getting the logger again replaces its context.
+func GetContextLogger(loggerName string, context func() logrus.Fields) *logrus.Logger {
+	return getLogger(loggerName, context)
+}
+
+func getLogger(loggerName string, context func() logrus.Fields) *logrus.Logger {
+	registry.Lock()
+	defer registry.Unlock()
+	if logger, ok := registry.loggers[loggerName]; ok {
+		if context != nil {
+			registry.contexts[loggerName].setContext(context)
+		}
+		return logger
 	}
 
+	logger := logrus.New()
+
+	// contextual fields are added before the entries are written by the configured sinks
+	ctxHook := &contextHook{}
+	ctxHook.setContext(context)
+	registry.contexts[loggerName] = ctxHook
+	logger.Hooks.Add(ctxHook)
+	logger.Hooks.Add(&sinkHook{name: loggerName})
 	logger.SetFormatter(&logFormatter{name: loggerName})
-	logger.SetLevel(logLevel)
-	logger.SetOutput(os.Stdout)
+	logger.SetLevel(levelOf(loggerName))
+	logger.SetReportCaller(reportCaller())
+	logger.SetOutput(ioutil.Discard)
 
+	registry.loggers[loggerName] = logger
 	return logger
 }
+
+// contextHook adds the fields of a contextual logger to its entries
+type contextHook struct {
+	mu      sync.RWMutex
+	context func() logrus.Fields
+}
+
+func (h *contextHook) setContext(context func() logrus.Fields) {
+	h.mu.Lock()
+	h.context = context
+	h.mu.Unlock()
+}
+
+func (h *contextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *contextHook) Fire(entry *logrus.Entry) error {
+	h.mu.RLock()
+	context := h.context
+	h.mu.RUnlock()
+	if context == nil {
+		return nil
+	}
+
+	// entry data may be shared with the entry logging was called on
+	data := make(logrus.Fields, len(entry.Data))
+	for k, v := range context() {
+		data[k] = v
+	}
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	entry.Data = data
+	return nil
+}
@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestFormatEntryTags(t *testing.T) {
+	tests := []struct {
+		name        string
+		logger      string
+		fields      logrus.Fields
+		wantProcess string
+		wantTag     string
+	}{
+		{"agent logger", "cagent", nil, "containeragent", "containeragent"},
+		{"component logger", "TMG-LFA", logrus.Fields{"component": "TMG-LFA", "qualifier": "lfa"}, "TMG-LFA", "lfa"},
+		{"component without qualifier", "TMG-LFA", logrus.Fields{"component": "TMG-LFA", "qualifier": ""}, "TMG-LFA", "TMG-LFA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &logrus.Entry{Time: time.Now(), Level: logrus.InfoLevel, Message: "started", Data: tt.fields}
+
+			b, err := formatEntry(tt.logger, FormatText, entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			line := string(b)
+			if want := "[metadata={process='" + tt.wantProcess + "',function='" + tt.logger + "',"; !strings.Contains(line, want) {
+				t.Errorf("text line %q does not contain %q", line, want)
+			}
+			if want := "] [INFO ] [" + tt.wantTag + "] " + tt.logger + " - started"; !strings.Contains(line, want) {
+				t.Errorf("text line %q does not contain %q", line, want)
+			}
+
+			b, err = formatEntry(tt.logger, FormatJSON, entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			record := map[string]interface{}{}
+			if err := json.Unmarshal(b, &record); err != nil {
+				t.Fatal(err)
+			}
+			if record["process"] != tt.wantProcess || record["logger"] != tt.logger {
+				t.Errorf("json process = %v logger = %v, want %s %s", record["process"], record["logger"], tt.wantProcess, tt.logger)
+			}
+		})
+	}
+}
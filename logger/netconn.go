@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+const (
+	dialTimeout = 2 * time.Second
+	// writeTimeout time a write may take before the peer is considered stalled
+	writeTimeout = 2 * time.Second
+	// redialDelay entries are dropped for this long after a failed connection attempt or a stalled write
+	redialDelay = 5 * time.Second
+)
+
+var errNotConnected = errors.New("not connected, waiting to reconnect")
+
+// netConn connection dialed on first use and re-established after write errors
+type netConn struct {
+	dial    func() (net.Conn, error)
+	conn    net.Conn
+	retryAt time.Time
+}
+
+// send writes b to the connection, reconnecting once when the write fails. A write to a stalled
+// peer is abandoned after writeTimeout and the connection is only dialed again after redialDelay.
+func (nc *netConn) send(b []byte) error {
+	err := nc.connect()
+	if err != nil {
+		return err
+	}
+	err = nc.write(b)
+	if err == nil {
+		return nil
+	}
+
+	nc.close()
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		nc.retryAt = time.Now().Add(redialDelay)
+		return err
+	}
+	if err = nc.connect(); err != nil {
+		return err
+	}
+	err = nc.write(b)
+	if err != nil {
+		nc.close()
+	}
+	return err
+}
+
+func (nc *netConn) write(b []byte) error {
+	err := nc.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err != nil {
+		return err
+	}
+	_, err = nc.conn.Write(b)
+	return err
+}
+
+func (nc *netConn) connect() error {
+	if nc.conn != nil {
+		return nil
+	}
+	if time.Now().Before(nc.retryAt) {
+		return errNotConnected
+	}
+
+	conn, err := nc.dial()
+	if err != nil {
+		nc.retryAt = time.Now().Add(redialDelay)
+		return err
+	}
+	nc.conn = conn
+	return nil
+}
+
+func (nc *netConn) close() error {
+	if nc.conn == nil {
+		return nil
+	}
+	err := nc.conn.Close()
+	nc.conn = nil
+	return err
+}
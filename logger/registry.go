@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LevelEnv environment variable with the log levels used before the configuration is loaded
+const LevelEnv = "MLCA_LOGGING_LEVEL"
+
+// defaultLevel level of loggers without an override when no level is configured
+const defaultLevel = logrus.InfoLevel
+
+// registry loggers created by GetLogger keyed by name, with the global level and per logger overrides
+var registry = struct {
+	sync.Mutex
+	loggers   map[string]*logrus.Logger
+	contexts  map[string]*contextHook
+	level     logrus.Level
+	overrides map[string]logrus.Level
+}{
+	loggers:   make(map[string]*logrus.Logger),
+	contexts:  make(map[string]*contextHook),
+	level:     defaultLevel,
+	overrides: make(map[string]logrus.Level),
+}
+
+func init() {
+	if spec := os.Getenv(LevelEnv); spec != "" {
+		if err := SetLevels(spec); err != nil {
+			fmt.Fprintf(os.Stderr, "ignoring %s: %s\n", LevelEnv, err)
+		}
+	}
+}
+
+// ParseLevels parses a level specification: an optional global level followed by comma
+// separated logger=level overrides, e.g. "info,jsonclient=warn,cagent=debug"
+func ParseLevels(spec string) (logrus.Level, map[string]logrus.Level, error) {
+	level := defaultLevel
+	overrides := make(map[string]logrus.Level)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, levelName := "", part
+		if i := strings.Index(part, "="); i >= 0 {
+			name, levelName = strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+1:])
+			if name == "" {
+				return level, nil, fmt.Errorf("missing logger name in [%s]", part)
+			}
+		}
+		l, err := logrus.ParseLevel(levelName)
+		if err != nil {
+			return level, nil, err
+		}
+
+		if name == "" {
+			level = l
+		} else {
+			overrides[name] = l
+		}
+	}
+	return level, overrides, nil
+}
+
+// SetLevels replaces the global level and the per logger overrides of all loggers, see ParseLevels
+func SetLevels(spec string) error {
+	level, overrides, err := ParseLevels(spec)
+	if err != nil {
+		return err
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	registry.level = level
+	registry.overrides = overrides
+	for name, logger := range registry.loggers {
+		logger.SetLevel(levelOf(name))
+	}
+	return nil
+}
+
+// SetLoggerLevel changes the level of a single logger at runtime
+func SetLoggerLevel(name, levelName string) error {
+	level, err := logrus.ParseLevel(levelName)
+	if err != nil {
+		return err
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	logger, ok := registry.loggers[name]
+	if !ok {
+		return fmt.Errorf("unknown logger [%s]", name)
+	}
+	registry.overrides[name] = level
+	logger.SetLevel(level)
+	return nil
+}
+
+// Level returns the global level
+func Level() string {
+	registry.Lock()
+	defer registry.Unlock()
+	return registry.level.String()
+}
+
+// Levels returns the effective level of every logger keyed by name
+func Levels() map[string]string {
+	registry.Lock()
+	defer registry.Unlock()
+	levels := make(map[string]string, len(registry.loggers))
+	for name, logger := range registry.loggers {
+		levels[name] = logger.GetLevel().String()
+	}
+	return levels
+}
+
+// LoggerNames returns the names of all loggers, sorted
+func LoggerNames() []string {
+	registry.Lock()
+	defer registry.Unlock()
+	names := make([]string, 0, len(registry.loggers))
+	for name := range registry.loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// levelOf returns the level of a logger, registry lock must be held
+func levelOf(name string) logrus.Level {
+	if level, ok := registry.overrides[name]; ok {
+		return level
+	}
+	return registry.level
+}
@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseLevels(t *testing.T) {
+	tests := []struct {
+		spec      string
+		level     logrus.Level
+		overrides map[string]logrus.Level
+		wantErr   bool
+	}{
+		{"", logrus.InfoLevel, map[string]logrus.Level{}, false},
+		{"debug", logrus.DebugLevel, map[string]logrus.Level{}, false},
+		{"WARN", logrus.WarnLevel, map[string]logrus.Level{}, false},
+		{"jsonclient=warn", logrus.InfoLevel, map[string]logrus.Level{"jsonclient": logrus.WarnLevel}, false},
+		{"info,jsonclient=warn,cagent=debug", logrus.InfoLevel, map[string]logrus.Level{"jsonclient": logrus.WarnLevel, "cagent": logrus.DebugLevel}, false},
+		{" error , TMG-LFA = trace ,", logrus.ErrorLevel, map[string]logrus.Level{"TMG-LFA": logrus.TraceLevel}, false},
+		{"cagent=debug,warn", logrus.WarnLevel, map[string]logrus.Level{"cagent": logrus.DebugLevel}, false},
+		{"cagent=debug,cagent=error", logrus.InfoLevel, map[string]logrus.Level{"cagent": logrus.ErrorLevel}, false},
+		{"verbose", 0, nil, true},
+		{"info,cagent=loud", 0, nil, true},
+		{"=debug", 0, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			level, overrides, err := ParseLevels(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseLevels(%q) expected an error", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLevels(%q) error = %v", tt.spec, err)
+			}
+			if level != tt.level || !reflect.DeepEqual(overrides, tt.overrides) {
+				t.Errorf("ParseLevels(%q) = %s %v, want %s %v", tt.spec, level, overrides, tt.level, tt.overrides)
+			}
+		})
+	}
+}
+
+func TestSetLevelsAtRuntime(t *testing.T) {
+	defer SetLevels("")
+	a, b := GetLogger("levels-a"), GetLogger("levels-b")
+
+	if err := SetLevels("warn,levels-b=debug,levels-c=trace"); err != nil {
+		t.Fatal(err)
+	}
+	if a.GetLevel() != logrus.WarnLevel || b.GetLevel() != logrus.DebugLevel {
+		t.Errorf("levels = %s %s, want warning debug", a.GetLevel(), b.GetLevel())
+	}
+	// an override applies to a logger created later
+	if c := GetLogger("levels-c"); c.GetLevel() != logrus.TraceLevel {
+		t.Errorf("level of a logger created after SetLevels = %s, want trace", c.GetLevel())
+	}
+
+	if err := SetLoggerLevel("levels-a", "error"); err != nil {
+		t.Fatal(err)
+	}
+	if levels := Levels(); levels["levels-a"] != "error" || levels["levels-b"] != "debug" || Level() != "warning" {
+		t.Errorf("levels = %v global %s", levels, Level())
+	}
+	if err := SetLoggerLevel("levels-unknown", "debug"); err == nil {
+		t.Error("SetLoggerLevel() of an unknown logger succeeded")
+	}
+	if err := SetLevels("info,levels-a=loud"); err == nil || a.GetLevel() != logrus.ErrorLevel {
+		t.Errorf("an invalid specification was applied: %v, level %s", err, a.GetLevel())
+	}
+
+	// replacing the levels drops the previous overrides
+	SetLevels("info")
+	if a.GetLevel() != logrus.InfoLevel || b.GetLevel() != logrus.InfoLevel {
+		t.Errorf("levels = %s %s, want info info", a.GetLevel(), b.GetLevel())
+	}
+}
@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMaxSize    = 100 // megabytes
+	defaultMaxBackups = 5
+)
+
+// rotatingFile writes entries to a file, rotated to file.1 ... file.<maxBackups> once it exceeds maxSize
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+}
+
+func newRotatingFile(path string, maxSize, maxBackups int) (*rotatingFile, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file sink requires a path")
+	}
+	if maxSize <= 0 {
+		maxSize = defaultMaxSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = defaultMaxBackups
+	}
+
+	rf := &rotatingFile{
+		path:       path,
+		maxSize:    int64(maxSize) * 1024 * 1024,
+		maxBackups: maxBackups,
+	}
+	err := rf.open()
+	if err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	err := os.MkdirAll(filepath.Dir(rf.path), 0755)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rf.file = file
+	rf.size = info.Size()
+	return nil
+}
+
+func (rf *rotatingFile) write(name string, entry *logrus.Entry, line []byte) error {
+	if rf.file == nil {
+		if err := rf.open(); err != nil {
+			return err
+		}
+	}
+	if rf.size > 0 && rf.size+int64(len(line)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := rf.file.Write(line)
+	rf.size += int64(n)
+	return err
+}
+
+// rotate shifts the backups by one, dropping the oldest, and starts a new file
+func (rf *rotatingFile) rotate() error {
+	rf.file.Close()
+	rf.file = nil
+
+	os.Remove(fmt.Sprintf("%s.%d", rf.path, rf.maxBackups))
+	for i := rf.maxBackups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", rf.path, i), fmt.Sprintf("%s.%d", rf.path, i+1))
+	}
+	err := os.Rename(rf.path, rf.path+".1")
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return rf.open()
+}
+
+func (rf *rotatingFile) close() error {
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
@@ -0,0 +1,238 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sink types
+const (
+	SinkStdout  = "stdout"
+	SinkFile    = "file"
+	SinkSyslog  = "syslog"
+	SinkForward = "forward"
+)
+
+// sinkQueueSize entries buffered by a network sink, further entries are dropped until the destination catches up
+const sinkQueueSize = 1024
+
+// SinkOptions settings of a log sink
+type SinkOptions struct {
+	Type   string
+	Level  string // most verbose level written, empty writes every entry enabled on the logger
+	Format string // text or json, empty follows the global format
+
+	// file
+	Path       string
+	MaxSize    int // megabytes written before the file is rotated
+	MaxBackups int // rotated files kept
+
+	// syslog and forward
+	Network            string // syslog: empty for the local daemon, udp, tcp or tls
+	Address            string // host:port
+	Tag                string // syslog app-name or forward tag
+	Facility           string // syslog facility, e.g. user or local0
+	CAFile             string // tls: certificates the server is verified with, system roots when empty
+	InsecureSkipVerify bool
+}
+
+// Sink destination log entries of every logger are written to
+type Sink struct {
+	// entries dropped while the queue was full, reported once a write succeeds again
+	dropped int64
+
+	name   string
+	level  logrus.Level
+	format string
+	writer sinkWriter
+
+	// network sinks write from a queue, so a slow or stalled destination does not block the loggers
+	queue   chan queuedEntry
+	drained chan struct{}
+
+	mu sync.Mutex
+	// failing suppresses repeated error reports until a write succeeds again
+	failing bool
+}
+
+// queuedEntry entry waiting to be written by a network sink
+type queuedEntry struct {
+	name  string
+	entry *logrus.Entry
+	line  []byte
+}
+
+// sinkWriter writes formatted entries to a destination
+type sinkWriter interface {
+	write(name string, entry *logrus.Entry, line []byte) error
+	close() error
+}
+
+// sinks in use, stdout until sinks are configured
+var sinks = struct {
+	sync.RWMutex
+	list []*Sink
+}{
+	list: []*Sink{{name: SinkStdout, level: logrus.TraceLevel, writer: stdoutWriter{}}},
+}
+
+// NewSink creates a sink, network connections are established on first write
+func NewSink(o SinkOptions) (*Sink, error) {
+	s := &Sink{
+		name:   o.Type,
+		level:  logrus.TraceLevel,
+		format: o.Format,
+	}
+	if o.Level != "" {
+		level, err := logrus.ParseLevel(o.Level)
+		if err != nil {
+			return nil, err
+		}
+		s.level = level
+	}
+	switch o.Format {
+	case "", FormatText, FormatJSON:
+	default:
+		return nil, fmt.Errorf("unknown log format [%s], must be one of %s, %s", o.Format, FormatText, FormatJSON)
+	}
+
+	var err error
+	switch o.Type {
+	case SinkStdout:
+		s.writer = stdoutWriter{}
+	case SinkFile:
+		s.name = o.Path
+		s.writer, err = newRotatingFile(o.Path, o.MaxSize, o.MaxBackups)
+	case SinkSyslog:
+		s.name = "syslog " + o.Network + " " + o.Address
+		s.writer, err = newSyslogWriter(o)
+	case SinkForward:
+		s.name = "forward " + o.Address
+		s.writer, err = newForwardWriter(o)
+	default:
+		err = fmt.Errorf("unknown sink type [%s], must be one of %s, %s, %s, %s", o.Type, SinkStdout, SinkFile, SinkSyslog, SinkForward)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if o.Type == SinkSyslog || o.Type == SinkForward {
+		s.startQueue()
+	}
+	return s, nil
+}
+
+// startQueue writes the entries of the sink from a bounded queue in the background
+func (s *Sink) startQueue() {
+	s.queue = make(chan queuedEntry, sinkQueueSize)
+	s.drained = make(chan struct{})
+	go func() {
+		defer close(s.drained)
+		for q := range s.queue {
+			s.writeLine(q.name, q.entry, q.line)
+		}
+	}()
+}
+
+// Close writes the queued entries and releases the file or connection of the sink
+func (s *Sink) Close() error {
+	if s.queue != nil {
+		close(s.queue)
+		<-s.drained
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writer.close()
+}
+
+func (s *Sink) write(name string, entry *logrus.Entry) {
+	if entry.Level > s.level {
+		return
+	}
+	format := s.format
+	if format == "" {
+		format = currentFormat()
+	}
+	line, err := formatEntry(name, format, entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "log sink %s: %s\n", s.name, err)
+		return
+	}
+
+	if s.queue != nil {
+		// logrus reuses the entry once the hooks returned
+		queued := *entry
+		select {
+		case s.queue <- queuedEntry{name: name, entry: &queued, line: line}:
+		default:
+			if atomic.AddInt64(&s.dropped, 1) == 1 {
+				fmt.Fprintf(os.Stderr, "log sink %s: queue is full, dropping entries\n", s.name)
+			}
+		}
+		return
+	}
+	s.writeLine(name, entry, line)
+}
+
+func (s *Sink) writeLine(name string, entry *logrus.Entry, line []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.writer.write(name, entry, line)
+	if err != nil {
+		// report the first failure only, the sink keeps retrying
+		if !s.failing {
+			fmt.Fprintf(os.Stderr, "log sink %s: %s\n", s.name, err)
+		}
+		s.failing = true
+		return
+	}
+	s.failing = false
+	if dropped := atomic.SwapInt64(&s.dropped, 0); dropped > 0 {
+		fmt.Fprintf(os.Stderr, "log sink %s: dropped %d entries while the queue was full\n", s.name, dropped)
+	}
+}
+
+// SetSinks replaces the sinks of all loggers and closes the previous ones
+func SetSinks(list []*Sink) {
+	sinks.Lock()
+	previous := sinks.list
+	sinks.list = list
+	sinks.Unlock()
+
+	for _, s := range previous {
+		s.Close()
+	}
+}
+
+// sinkHook writes the entries of a logger to every sink
+type sinkHook struct {
+	name string
+}
+
+func (h *sinkHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *sinkHook) Fire(entry *logrus.Entry) error {
+	sinks.RLock()
+	defer sinks.RUnlock()
+	for _, s := range sinks.list {
+		s.write(h.name, entry)
+	}
+	return nil
+}
+
+// stdoutWriter writes entries to standard output
+type stdoutWriter struct{}
+
+func (stdoutWriter) write(name string, entry *logrus.Entry, line []byte) error {
+	_, err := os.Stdout.Write(line)
+	return err
+}
+
+func (stdoutWriter) close() error {
+	return nil
+}
@@ -0,0 +1,116 @@
+package logger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSyslogSinkOverTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := NewSink(SinkOptions{Type: SinkSyslog, Network: syslogTCP, Address: l.Addr().String(), Facility: "local0", Level: "info", Format: FormatText})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.write("cagent", &logrus.Entry{Time: time.Now(), Level: logrus.DebugLevel, Message: "below the sink level"})
+	s.write("cagent", &logrus.Entry{Time: time.Now(), Level: logrus.InfoLevel, Message: "registered"})
+	s.write("cagent", &logrus.Entry{Time: time.Now(), Level: logrus.ErrorLevel, Message: "multi\nline"})
+	s.Close()
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+
+	// messages are framed by their octet count, a message may contain newlines
+	var messages []string
+	for {
+		count, err := r.ReadString(' ')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil {
+			t.Fatalf("frame starts with %q, want the octet count", count)
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, string(msg))
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("received %d messages, want 2: %q", len(messages), messages)
+	}
+	// <local0*8+severity>1 TIMESTAMP HOSTNAME mlca PID cagent - LINE
+	header := fmt.Sprintf(` \S+ \S+ mlca %d cagent - .*\] cagent - `, os.Getpid())
+	for i, want := range []string{`^<134>1` + header + `registered$`, `(?s)^<131>1` + header + "multi\nline$"} {
+		if !regexp.MustCompile(want).MatchString(messages[i]) {
+			t.Errorf("message %d = %q, want %s", i, messages[i], want)
+		}
+	}
+}
+
+func TestNetworkSinkDoesNotBlockTheLoggers(t *testing.T) {
+	// nothing listens on the address of a closed listener
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	l.Close()
+
+	s, err := NewSink(SinkOptions{Type: SinkForward, Address: address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i := 0; i < 3*sinkQueueSize; i++ {
+		s.write("cagent", &logrus.Entry{Time: time.Now(), Level: logrus.InfoLevel, Message: "unreachable"})
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("writing to an unreachable destination took %s", elapsed)
+	}
+	// queued entries are dropped without dialing again until the redial delay expired
+	s.Close()
+	if time.Since(start) > redialDelay {
+		t.Errorf("closing the sink took longer than the redial delay")
+	}
+}
+
+func TestNewSinkRejectsInvalidOptions(t *testing.T) {
+	for _, o := range []SinkOptions{
+		{Type: "kafka"},
+		{Type: SinkStdout, Level: "loud"},
+		{Type: SinkStdout, Format: "xml"},
+		{Type: SinkSyslog, Network: "sctp", Address: "localhost:514"},
+		{Type: SinkSyslog, Network: syslogTCP},
+		{Type: SinkSyslog, Network: syslogUDP, Address: "localhost:514", Facility: "local9"},
+		{Type: SinkForward},
+	} {
+		if _, err := NewSink(o); err == nil {
+			t.Errorf("NewSink(%+v) succeeded", o)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+// syslog networks
+const (
+	syslogLocal = ""
+	syslogUDP   = "udp"
+	syslogTCP   = "tcp"
+	syslogTLS   = "tls"
+)
+
+const defaultSyslogTag = "mlca"
+
+// local syslog daemon sockets
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogWriter writes RFC 5424 messages to the local daemon or a remote server,
+// messages sent over tcp and tls are framed by octet counting (RFC 6587)
+type syslogWriter struct {
+	network  string
+	tag      string
+	facility int
+	hostname string
+	pid      int
+	stream   bool
+	conn     *netConn
+}
+
+func newSyslogWriter(o SinkOptions) (*syslogWriter, error) {
+	facility := syslogFacilities["user"]
+	if o.Facility != "" {
+		f, ok := syslogFacilities[o.Facility]
+		if !ok {
+			return nil, fmt.Errorf("unknown syslog facility [%s]", o.Facility)
+		}
+		facility = f
+	}
+	tag := o.Tag
+	if tag == "" {
+		tag = defaultSyslogTag
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	sw := &syslogWriter{
+		network:  o.Network,
+		tag:      tag,
+		facility: facility,
+		hostname: hostname,
+		pid:      os.Getpid(),
+	}
+
+	var dial func() (net.Conn, error)
+	switch o.Network {
+	case syslogLocal:
+		dial = dialLocalSyslog
+	case syslogUDP, syslogTCP:
+		if o.Address == "" {
+			return nil, fmt.Errorf("%s syslog sink requires an address", o.Network)
+		}
+		sw.stream = o.Network == syslogTCP
+		dial = func() (net.Conn, error) {
+			return net.DialTimeout(o.Network, o.Address, dialTimeout)
+		}
+	case syslogTLS:
+		if o.Address == "" {
+			return nil, fmt.Errorf("tls syslog sink requires an address")
+		}
+		tlsConfig, err := newTLSConfig(o.CAFile, o.InsecureSkipVerify)
+		if err != nil {
+			return nil, err
+		}
+		sw.stream = true
+		dial = func() (net.Conn, error) {
+			return tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", o.Address, tlsConfig)
+		}
+	default:
+		return nil, fmt.Errorf("unknown syslog network [%s], must be empty for the local daemon or one of udp, tcp, tls", o.Network)
+	}
+	sw.conn = &netConn{dial: dial}
+	return sw, nil
+}
+
+// dialLocalSyslog connects to the first reachable local syslog socket
+func dialLocalSyslog() (net.Conn, error) {
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range syslogSockets {
+			conn, err := net.DialTimeout(network, path, dialTimeout)
+			if err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("local syslog daemon is not reachable")
+}
+
+func newTLSConfig(caFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+	if caFile == "" {
+		return tlsConfig, nil
+	}
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.RootCAs = x509.NewCertPool()
+	if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return tlsConfig, nil
+}
+
+func (sw *syslogWriter) write(name string, entry *logrus.Entry, line []byte) error {
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d %s - %s",
+		sw.facility*8+syslogSeverity(entry.Level),
+		timestamp(entry.Time),
+		sw.hostname, sw.tag, sw.pid, name,
+		bytes.TrimRight(line, "\n"))
+	if sw.stream {
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+	return sw.conn.send([]byte(msg))
+}
+
+func (sw *syslogWriter) close() error {
+	return sw.conn.close()
+}
+
+// syslogSeverity maps logrus levels to syslog severities
+func syslogSeverity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return 2
+	case logrus.ErrorLevel:
+		return 3
+	case logrus.WarnLevel:
+		return 4
+	case logrus.InfoLevel:
+		return 6
+	}
+	return 7
+}